@@ -0,0 +1,440 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const indexFile = "index.json"
+const partialSuffix = ".part"
+
+// staleAge is how long a partial download has to go unmodified before it is considered abandoned. Younger ones may
+// still be recorded to by another process using the same directory.
+const staleAge = time.Hour
+
+// opened holds the Caches opened in this process by their absolute directory, so that all users of a directory share
+// one index.
+var (
+	openedLock sync.Mutex
+	opened     = make(map[string]*Cache)
+)
+
+// Cache is a size bounded on-disk cache of downloaded streams. Once the total size of all entries exceeds the maximum,
+// the least recently used entries are evicted. Every entry is verified against its recorded size and checksum before
+// being served, so partial or corrupted files are never returned.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	lock    sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	Key        string    `json:"key"`
+	File       string    `json:"file"`
+	Size       int64     `json:"size"`
+	Checksum   string    `json:"checksum"`
+	LastAccess time.Time `json:"last_access"`
+	// Meta holds what was returned along with the data by the fetch passed to OpenMeta.
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// New opens the cache stored in dir, creating it if needed. sizeMB is the maximum total size of all entries in MB.
+// Abandoned partial downloads and files that aren't tracked by the cache index are removed. If dir is already open in
+// this process, the open Cache is returned instead, keeping its original size limit, since two Caches can't share a
+// directory.
+func New(dir string, sizeMB int) (*Cache, error) {
+	if sizeMB <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	openedLock.Lock()
+	defer openedLock.Unlock()
+
+	if c, ok := opened[dir]; ok {
+		return c, nil
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		dir:     dir,
+		maxSize: int64(sizeMB) * 1024 * 1024,
+		entries: make(map[string]*entry),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.evict()
+	if err = c.save(); err != nil {
+		return nil, err
+	}
+
+	opened[dir] = c
+
+	return c, nil
+}
+
+// Open returns a reader for the entry stored under key. On a cache miss, or if the stored entry fails verification,
+// fetch is called instead and the data it returns is recorded while it is read. The recording is only stored once the
+// returned reader has been read to io.EOF and closed.
+func (c *Cache) Open(key string, fetch func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	r, _, err := c.OpenMeta(key, func() (io.ReadCloser, map[string]string, error) {
+		r, err := fetch()
+		return r, nil, err
+	})
+
+	return r, err
+}
+
+// OpenMeta works like Open, but also stores metadata along with the entry, for things that are known while fetching
+// but can't be read back from the data itself. On a cache miss, the metadata returned by fetch is returned and stored
+// with the recording. On a hit, the stored metadata is returned.
+func (c *Cache) OpenMeta(key string, fetch func() (io.ReadCloser, map[string]string, error)) (io.ReadCloser,
+	map[string]string, error) {
+	if f, meta, ok := c.get(key); ok {
+		return f, meta, nil
+	}
+
+	r, meta, err := fetch()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, fileName(key)+"-*"+partialSuffix)
+	if err != nil {
+		// Failing to record shouldn't prevent playback.
+		return r, meta, nil
+	}
+
+	return &recorder{cache: c, key: key, meta: maps.Clone(meta), src: r, tmp: tmp, hash: sha256.New()}, meta, nil
+}
+
+// Remove deletes the entry stored under key, if any.
+func (c *Cache) Remove(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.remove(e)
+
+	return c.save()
+}
+
+// Size returns the total size of all entries in bytes.
+func (c *Cache) Size() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.size()
+}
+
+// get opens and verifies the entry stored under key, and returns it along with its metadata. Entries that fail
+// verification are removed. The file is verified without holding the lock, since hashing large entries would otherwise
+// hold up every other lookup.
+func (c *Cache) get(key string) (*os.File, map[string]string, bool) {
+	c.lock.Lock()
+	e, ok := c.entries[key]
+	var expected entry
+	if ok {
+		expected = *e
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	f, err := os.Open(filepath.Join(c.dir, expected.File))
+	valid := err == nil && verify(f, &expected)
+	if err == nil && !valid {
+		_ = f.Close()
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// The entry may have been replaced or removed while it was verified, in which case it is left alone.
+	if c.entries[key] != e {
+		if valid {
+			_ = f.Close()
+		}
+		return nil, nil, false
+	}
+
+	if !valid {
+		c.remove(e)
+		_ = c.save()
+		return nil, nil, false
+	}
+
+	e.LastAccess = time.Now()
+	_ = c.save()
+
+	return f, maps.Clone(e.Meta), true
+}
+
+// put moves the completed recording at tmpPath into the cache under key.
+func (c *Cache) put(key string, tmpPath string, size int64, checksum string, meta map[string]string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if old, ok := c.entries[key]; ok {
+		c.remove(old)
+	}
+
+	e := &entry{
+		Key:        key,
+		File:       fileName(key),
+		Size:       size,
+		Checksum:   checksum,
+		LastAccess: time.Now(),
+		Meta:       meta,
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(c.dir, e.File)); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	c.entries[key] = e
+	c.evict()
+
+	return c.save()
+}
+
+// evict removes the least recently used entries until the total size is within bounds. The lock must be held.
+func (c *Cache) evict() {
+	total := c.size()
+	if total <= c.maxSize {
+		return
+	}
+
+	entries := make([]*entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastAccess.Before(entries[j].LastAccess)
+	})
+
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+
+		c.remove(e)
+		total -= e.Size
+	}
+}
+
+// remove deletes e from disk and from the index. The lock must be held.
+func (c *Cache) remove(e *entry) {
+	_ = os.Remove(filepath.Join(c.dir, e.File))
+	delete(c.entries, e.Key)
+}
+
+// size returns the total size of all entries. The lock must be held.
+func (c *Cache) size() int64 {
+	var total int64
+	for _, e := range c.entries {
+		total += e.Size
+	}
+
+	return total
+}
+
+// load reads the index from disk and removes everything in the cache directory that it doesn't reference, except for
+// partial downloads that may still be in progress.
+func (c *Cache) load() error {
+	b, err := os.ReadFile(filepath.Join(c.dir, indexFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var entries []*entry
+	if len(b) > 0 {
+		if err = json.Unmarshal(b, &entries); err != nil {
+			// A corrupted index can't be trusted. Starting over is the only safe option.
+			entries = nil
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, e := range entries {
+		info, err := os.Stat(filepath.Join(c.dir, e.File))
+		if err != nil || info.Size() != e.Size {
+			continue
+		}
+
+		c.entries[e.Key] = e
+		known[e.File] = true
+	}
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		// The directory is supplied by the user, so only files the cache could have created are removed.
+		if file.IsDir() || file.Name() == indexFile || known[file.Name()] || !ownedFile(file.Name()) {
+			continue
+		}
+
+		if strings.HasSuffix(file.Name(), partialSuffix) {
+			if info, err := file.Info(); err != nil || time.Since(info.ModTime()) < staleAge {
+				continue
+			}
+		}
+
+		_ = os.Remove(filepath.Join(c.dir, file.Name()))
+	}
+
+	return nil
+}
+
+// save writes the index to disk. The lock must be held.
+func (c *Cache) save() error {
+	entries := make([]*entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(c.dir, indexFile+partialSuffix)
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(c.dir, indexFile))
+}
+
+// verify checks that f matches the size and checksum recorded in e. The file offset is reset to the start afterward.
+func verify(f *os.File, e *entry) bool {
+	info, err := f.Stat()
+	if err != nil || info.Size() != e.Size {
+		return false
+	}
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return false
+	}
+
+	if !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), e.Checksum) {
+		return false
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+
+	return err == nil
+}
+
+// fileName returns the name of the file used to store key.
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ownedFile reports whether name is one the cache creates: an entry, a recording of one or a partial index.
+func ownedFile(name string) bool {
+	if name == indexFile+partialSuffix {
+		return true
+	}
+
+	if recording, ok := strings.CutSuffix(name, partialSuffix); ok {
+		var found bool
+		if name, _, found = strings.Cut(recording, "-"); !found {
+			return false
+		}
+	}
+
+	if len(name) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+
+	return err == nil
+}
+
+// recorder passes reads through from src while writing them to tmp. The recording is committed to the cache once src
+// has been read to io.EOF and the recorder is closed.
+type recorder struct {
+	cache *Cache
+	key   string
+	meta  map[string]string
+	src   io.ReadCloser
+	tmp   *os.File
+	hash  hash.Hash
+	size  int64
+	done  bool
+	err   error
+}
+
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	if n > 0 && r.err == nil {
+		if _, r.err = r.tmp.Write(p[:n]); r.err == nil {
+			r.hash.Write(p[:n])
+			r.size += int64(n)
+		}
+	}
+
+	if err == io.EOF {
+		r.done = true
+	}
+
+	return n, err
+}
+
+func (r *recorder) Close() error {
+	err := r.src.Close()
+
+	tmpPath := r.tmp.Name()
+	if closeErr := r.tmp.Close(); closeErr != nil && r.err == nil {
+		r.err = closeErr
+	}
+
+	if !r.done || r.err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	_ = r.cache.put(r.key, tmpPath, r.size, hex.EncodeToString(r.hash.Sum(nil)), r.meta)
+
+	return err
+}
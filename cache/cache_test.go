@@ -0,0 +1,224 @@
+package cache_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo/cache"
+)
+
+func TestCache_Open(t *testing.T) {
+	type test struct {
+		readAll bool
+		corrupt bool
+		fetches int
+	}
+
+	tests := map[string]test{
+		"hit":     {readAll: true, corrupt: false, fetches: 1},
+		"partial": {readAll: false, corrupt: false, fetches: 2},
+		"corrupt": {readAll: true, corrupt: true, fetches: 2},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			c, err := cache.New(dir, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data := bytes.Repeat([]byte("apollo"), 100)
+			fetches := 0
+			fetch := func() (io.ReadCloser, error) {
+				fetches++
+				return io.NopCloser(bytes.NewReader(data)), nil
+			}
+
+			r, err := c.Open("key", fetch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tst.readAll {
+				_, _ = io.ReadAll(r)
+			} else {
+				_, _ = r.Read(make([]byte, 10))
+			}
+			_ = r.Close()
+
+			if tst.corrupt {
+				files, _ := filepath.Glob(filepath.Join(dir, "*"))
+				for _, file := range files {
+					if filepath.Base(file) != "index.json" {
+						_ = os.WriteFile(file, bytes.Repeat([]byte("x"), len(data)), 0644)
+					}
+				}
+			}
+
+			r, err = c.Open("key", fetch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(r)
+			_ = r.Close()
+
+			if !bytes.Equal(got, data) {
+				t.Fatalf("expected %d bytes of original data; got %d bytes", len(data), len(got))
+			}
+			if fetches != tst.fetches {
+				t.Fatalf("expected %d fetches; got %d", tst.fetches, fetches)
+			}
+		})
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 400*1024)
+	fetch := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		r, _ := c.Open(key, fetch)
+		_, _ = io.ReadAll(r)
+		_ = r.Close()
+	}
+
+	if size := c.Size(); size != 2*int64(len(data)) {
+		t.Fatalf("expected cache size %d; got %d", 2*len(data), size)
+	}
+
+	fetched := false
+	r, _ := c.Open("a", func() (io.ReadCloser, error) {
+		fetched = true
+		return fetch()
+	})
+	_ = r.Close()
+
+	if !fetched {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+}
+
+func TestNew_SameDir(t *testing.T) {
+	dir := t.TempDir()
+	first, err := cache.New(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("apollo"), 100)
+	r, err := first.Open("key", func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = r.Read(make([]byte, 10))
+
+	second, err := cache.New(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Fatal("expected the open cache to be returned for the same directory")
+	}
+
+	_, _ = io.ReadAll(r)
+	_ = r.Close()
+
+	r, err = second.Open("key", func() (io.ReadCloser, error) {
+		t.Fatal("expected recording of the first cache to be served")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	_ = r.Close()
+
+	if !bytes.Equal(got, data) {
+		t.Fatalf("expected %d bytes of original data; got %d bytes", len(data), len(got))
+	}
+}
+
+func TestNew_Cleanup(t *testing.T) {
+	type test struct {
+		name    string
+		stale   bool
+		removed bool
+	}
+
+	entry := strings.Repeat("0a", 32)
+	tests := map[string]test{
+		"unrelated":       {name: "notes.txt", stale: true, removed: false},
+		"unrelated_part":  {name: "notes.part", stale: true, removed: false},
+		"unindexed_entry": {name: entry, stale: false, removed: true},
+		"stale_recording": {name: entry + "-123.part", stale: true, removed: true},
+		"young_recording": {name: entry + "-123.part", stale: false, removed: false},
+		"stale_index":     {name: "index.json.part", stale: true, removed: true},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, tst.name)
+			if err := os.WriteFile(file, []byte("apollo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tst.stale {
+				old := time.Now().Add(-2 * time.Hour)
+				if err := os.Chtimes(file, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := cache.New(dir, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := os.Stat(file)
+			if removed := errors.Is(err, os.ErrNotExist); removed != tst.removed {
+				t.Fatalf("expected removed to be %t; got %t", tst.removed, removed)
+			}
+		})
+	}
+}
+
+func TestCache_OpenMeta(t *testing.T) {
+	c, err := cache.New(t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("apollo"), 100)
+	want := map[string]string{"gain": "-3.5"}
+	fetch := func() (io.ReadCloser, map[string]string, error) {
+		return io.NopCloser(bytes.NewReader(data)), want, nil
+	}
+
+	for _, step := range []string{"miss", "hit"} {
+		r, meta, err := c.OpenMeta("key", fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.ReadAll(r)
+		_ = r.Close()
+
+		if !reflect.DeepEqual(meta, want) {
+			t.Fatalf("%s: expected metadata %v; got %v", step, want, meta)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package cache
+
+import (
+	"errors"
+)
+
+var ErrInvalidSize = errors.New("cache: size must be greater than 0")
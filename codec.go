@@ -2,6 +2,7 @@ package apollo
 
 import (
 	"io"
+	"time"
 )
 
 // Codec represents any encoder or decoder that can Read into a stream of bytes.
@@ -10,6 +11,39 @@ type Codec interface {
 	io.ReadCloser
 }
 
+// SeekableCodec is a Codec that can start processing a stream at an offset from its beginning.
+type SeekableCodec interface {
+	Codec
+	// OpenAt behaves like Open, but the first data read corresponds to offset.
+	OpenAt(r io.Reader, offset time.Duration) error
+}
+
+// TimedCodec is a Codec that can report the media time of the data it has returned so far, e.g. from granule
+// positions or sample counts.
+type TimedCodec interface {
+	Codec
+	// Position returns the media time of all data read since the codec was opened, including the offset it was opened
+	// at. The boolean is false if the position isn't known.
+	Position() (time.Duration, bool)
+}
+
+// MetadataCodec is a Codec whose stream can change its metadata while being read, e.g. a chained ogg stream or an
+// internet radio that announces the current song.
+type MetadataCodec interface {
+	Codec
+	// MetadataChanged reports whether the metadata changed since it was last called.
+	MetadataChanged() bool
+}
+
+// GainCodec is a Codec whose stream asks for a gain to be applied to it that the codec leaves to its consumer, e.g. the
+// output gain of opus streams read with ogg.Decoder. The player adds it to the gain of the Playable, which like all
+// gain is only applied when an encoder is set or PCM output is enabled.
+type GainCodec interface {
+	Codec
+	// OutputGain returns the gain in dB. It may change while the stream is read.
+	OutputGain() float64
+}
+
 type NopCodec struct {
 	r io.Reader
 }
@@ -1,18 +1,101 @@
 package apollo
 
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/olympus-go/apollo/cache"
+)
+
 type PlayerConfig struct {
-	// CacheSize sets the max file cache size in MB. File caching is disabled if 0.
+	// CacheSize sets the max file cache size in MB. File caching is disabled if 0. Only Playables implementing
+	// Identifiable are cached.
 	// Defaults to 0.
 	CacheSize int `json:"cache_size"`
 
 	// CacheDir sets the directory to be used for cache files. Is only used when CacheSize > 0.
-	// Defaults to ${HOME}/.apollo/spotify/cache/ on linux/macos and %userprofile%\AppData\local\apollo\spotify\cache\ on windows.
+	// Defaults to apollo/cache/ inside the user cache directory, e.g. ${HOME}/.cache/apollo/cache/ on linux.
 	CacheDir string `json:"cache_dir"`
 
+	// Cache sets a cache to use instead of opening one from CacheSize and CacheDir, so that one cache can be shared,
+	// e.g. between several Players and a spotify.Session.
+	// Defaults to nil.
+	Cache *cache.Cache `json:"-"`
+
 	// TargetAudioFormat sets the target audio format when the default ffmpeg transcoder is used. This is ignored if
 	// a custom transcoder is supplied.
 	TargetAudioFormat string
 
 	// PacketBuffer sets the size of the byte buffer used to read packets from enqueued Playable
 	PacketBuffer int `json:"packet_buffer"`
+
+	// PrefetchLookAhead sets how long before the end of the current Playable the next one in queue starts being
+	// downloaded and opened in the background. The next codec is only opened early if it is a different Codec
+	// instance than the current one, so entries should be enqueued with their own codec for fully gapless
+	// transitions. Prefetching is disabled if 0.
+	// Defaults to 0.
+	PrefetchLookAhead time.Duration `json:"prefetch_look_ahead"`
+
+	// Crossfade sets how long the end of a Playable overlaps with the beginning of the next one. It is only applied
+	// when an encoder is set with Player.SetEncoder or PCM output is enabled with Player.SetPCMOutput, and only between
+	// Playables that finish on their own. Setting PrefetchLookAhead to at least Crossfade keeps the next Playable's
+	// download from delaying the fade.
+	// Defaults to 0.
+	Crossfade time.Duration `json:"crossfade"`
+
+	// OpusPackets makes the out channel deliver exactly one opus packet per message, as voice transports expect. It is
+	// meant for queue entries decoded with ogg.Decoder. Their OpusHead and OpusTags packets are skipped, and any other
+	// output that isn't a valid opus packet is logged and dropped. Put a Packetizer on Player.Out to add sequence
+	// numbers, timestamps and silence frames. It is ignored when an encoder is set or PCM output is enabled.
+	// Defaults to false.
+	OpusPackets bool `json:"opus_packets"`
+
+	// PCMFormat sets the format of the PCM that queue entry codecs output when an encoder is set or PCM output is
+	// enabled.
+	// Defaults to DefaultPCMFormat.
+	PCMFormat PCMFormat `json:"pcm_format"`
+
+	// FrameDuration sets the length of the PCM frames read from queue entries when an encoder is set or PCM output is
+	// enabled. With PCM output, it is also the length of every packet sent on the out channel.
+	// Defaults to 20ms.
+	FrameDuration time.Duration `json:"frame_duration"`
+
+	// Realtime paces the out channel to play speed, so that consumers that buffer don't race ahead of what is actually
+	// being heard. Audio is timed by its PCM length with an encoder set or PCM output enabled, and otherwise by how far
+	// the position of a TimedCodec advances. Output of other codecs is sent right away. Player.Underruns and
+	// Player.Overruns count how often the pacing fell behind.
+	// Defaults to false.
+	Realtime bool `json:"realtime"`
+
+	// JitterBuffer sets how far ahead of play speed audio is sent when Realtime is set, which absorbs hiccups on either
+	// side of the out channel. Negative values send audio exactly when it is due.
+	// Defaults to DefaultJitterBuffer.
+	JitterBuffer time.Duration `json:"jitter_buffer"`
+
+	// Normalize enables loudness normalization. Each Playable is adjusted to TargetLoudness, using the loudness it
+	// provides as a LoudnessProvider, or otherwise measuring it in the background with the analyzer set by
+	// Player.SetLoudnessAnalyzer. Playables with unknown loudness are played unchanged. Like volume, normalization is
+	// only applied when an encoder is set with Player.SetEncoder or PCM output is enabled with Player.SetPCMOutput.
+	// Defaults to false.
+	Normalize bool `json:"normalize"`
+
+	// TargetLoudness sets the integrated loudness in LUFS that Playables are normalized to.
+	// Defaults to DefaultTargetLoudness.
+	TargetLoudness float64 `json:"target_loudness"`
+
+	// LoudnessFile sets the file that loudness values of Identifiable Playables are stored in, so that they don't have
+	// to be read or measured again after a restart. Values are only kept in memory if empty.
+	// Defaults to "".
+	LoudnessFile string `json:"loudness_file"`
+}
+
+// defaultCacheDir returns the directory used for cache files when PlayerConfig.CacheDir is unset.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = "."
+	}
+
+	return filepath.Join(dir, "apollo", "cache")
 }
@@ -0,0 +1,119 @@
+package apollo
+
+import (
+	"math"
+)
+
+// Crossfader overlaps the end of one PCM stream with the beginning of the next. It works as a delay line: the most
+// recent audio of the current stream is held back, so that when the stream ends its tail can be mixed into the head of
+// the following stream with an equal power fade.
+//
+// A Crossfader isn't safe for concurrent use.
+type Crossfader struct {
+	format PCMFormat
+	length int
+
+	// delay holds the most recent audio of the current stream that hasn't been output yet.
+	delay []byte
+	// fading holds the tail of the previous stream. mixed is how much of it has been mixed into the current stream.
+	fading []byte
+	mixed  int
+}
+
+// NewCrossfader creates a Crossfader that overlaps consecutive streams of format by length bytes. A length of 0 passes
+// audio straight through.
+func NewCrossfader(format PCMFormat, length int) *Crossfader {
+	align := format.BlockAlign()
+
+	return &Crossfader{
+		format: format,
+		length: length - length%align,
+	}
+}
+
+// Write adds PCM of the current stream and returns the audio that is ready to be output, which may be empty. p must be
+// a multiple of the format's BlockAlign and may be modified.
+func (c *Crossfader) Write(p []byte) []byte {
+	if c.mixed < len(c.fading) {
+		n := min(len(p), len(c.fading)-c.mixed)
+		mix(c.format.SampleFormat, p[:n], c.fading[c.mixed:c.mixed+n], c.mixed, len(c.fading))
+
+		c.mixed += n
+		if c.mixed == len(c.fading) {
+			c.fading = nil
+			c.mixed = 0
+		}
+	}
+
+	c.delay = append(c.delay, p...)
+	if len(c.delay) <= c.length {
+		return nil
+	}
+
+	n := len(c.delay) - c.length
+	out := make([]byte, n)
+	copy(out, c.delay[:n])
+	c.delay = append(c.delay[:0], c.delay[n:]...)
+
+	return out
+}
+
+// End marks the current stream as finished. Its held back tail will be faded into the next stream written.
+func (c *Crossfader) End() {
+	c.delay = append(c.delay, c.fadeOut()...)
+	c.fading = c.delay
+	c.mixed = 0
+	c.delay = nil
+}
+
+// Flush returns all held back audio, fading out whatever was still waiting for a next stream.
+func (c *Crossfader) Flush() []byte {
+	out := append(c.delay, c.fadeOut()...)
+	c.Reset()
+
+	return out
+}
+
+// Reset drops all held back audio.
+func (c *Crossfader) Reset() {
+	c.delay = nil
+	c.fading = nil
+	c.mixed = 0
+}
+
+// fadeOut returns the part of fading that hasn't been mixed yet, faded out as if mixed with silence.
+func (c *Crossfader) fadeOut() []byte {
+	if c.mixed >= len(c.fading) {
+		return nil
+	}
+
+	rest := make([]byte, len(c.fading)-c.mixed)
+	mix(c.format.SampleFormat, rest, c.fading[c.mixed:], c.mixed, len(c.fading))
+	c.fading = nil
+	c.mixed = 0
+
+	return rest
+}
+
+// mix fades dst in while fading src out, storing the result in dst. Both hold samples of format s located at offset
+// bytes into a fade that is length bytes long.
+func mix(s SampleFormat, dst []byte, src []byte, offset int, length int) {
+	size := s.Size()
+	for i := 0; i+size <= len(dst); i += size {
+		t := float64(offset+i) / float64(length) * math.Pi / 2
+
+		s.store(dst[i:], s.load(dst[i:])*math.Sin(t)+s.load(src[i:])*math.Cos(t))
+	}
+}
+
+// clamp16 rounds v to the nearest value representable by an int16.
+func clamp16(v float64) int16 {
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	} else if v < math.MinInt16 {
+		return math.MinInt16
+	}
+
+	return int16(v)
+}
@@ -0,0 +1,127 @@
+package apollo_test
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestCrossfader(t *testing.T) {
+	type test struct {
+		// length is the crossfade length in samples.
+		length  int
+		streams [][]float64
+		// end marks the last stream as finished before flushing. The other streams are always ended.
+		end      bool
+		expected []float64
+	}
+
+	ones := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+	zeros := []float64{0, 0, 0, 0, 0, 0, 0, 0}
+	// fade is how a stream of ones fades out over 4 samples.
+	fade := []float64{1, math.Cos(math.Pi / 8), math.Cos(math.Pi / 4), math.Cos(3 * math.Pi / 8)}
+
+	tests := map[string]test{
+		"pass_through": {
+			length:   0,
+			streams:  [][]float64{{1, 2, 3}, {4, 5}},
+			expected: []float64{1, 2, 3, 4, 5},
+		},
+		"delay": {
+			length:   2,
+			streams:  [][]float64{{1, 2, 3, 4}},
+			expected: []float64{1, 2, 3, 4},
+		},
+		"crossfade": {
+			length:   4,
+			streams:  [][]float64{ones, zeros},
+			expected: append(append(append([]float64{}, ones[:4]...), fade...), zeros[:4]...),
+		},
+		"fade_in": {
+			length:  4,
+			streams: [][]float64{zeros, ones},
+			expected: append(append([]float64{0, 0, 0, 0}, 0, math.Sin(math.Pi/8), math.Sin(math.Pi/4),
+				math.Sin(3*math.Pi/8)), ones[4:]...),
+		},
+		"fade_out": {
+			length:   4,
+			streams:  [][]float64{ones},
+			end:      true,
+			expected: append(append([]float64{}, ones[:4]...), fade...),
+		},
+		// A stream shorter than the crossfade is faded over its whole length.
+		"short_stream": {
+			length:   4,
+			streams:  [][]float64{{1, 1}, zeros},
+			expected: append([]float64{1, math.Cos(math.Pi / 4)}, zeros[2:]...),
+		},
+	}
+
+	format := apollo.PCMFormat{SampleRate: 4, Channels: 1}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := apollo.NewCrossfader(format, tst.length*format.BlockAlign())
+
+			var out []byte
+			for i, stream := range tst.streams {
+				out = append(out, c.Write(pcm(stream...))...)
+				if i < len(tst.streams)-1 || tst.end {
+					c.End()
+				}
+			}
+			out = append(out, c.Flush()...)
+
+			samples := fromPCM(out)
+			if len(samples) != len(tst.expected) {
+				t.Fatalf("expected %d samples; got %d", len(tst.expected), len(samples))
+			}
+			for i := range samples {
+				if math.Abs(samples[i]-tst.expected[i]) > 1e-3 {
+					t.Fatalf("expected %v; got %v", tst.expected, samples)
+				}
+			}
+		})
+	}
+}
+
+func TestCrossfader_Reset(t *testing.T) {
+	format := apollo.PCMFormat{SampleRate: 4, Channels: 1}
+	c := apollo.NewCrossfader(format, 4*format.BlockAlign())
+
+	_ = c.Write(pcm(1, 1, 1, 1, 1, 1))
+	c.End()
+	c.Reset()
+
+	if out := fromPCM(c.Write(pcm(0, 0, 0, 0, 0))); len(out) != 1 || out[0] != 0 {
+		t.Fatalf("expected [0]; got %v", out)
+	}
+	if out := fromPCM(c.Flush()); len(out) != 4 {
+		t.Fatalf("expected 4 samples; got %d", len(out))
+	}
+}
+
+// sampleScale is the s16le value a sample of 1 is encoded as, leaving room for the larger samples used by the tests.
+const sampleScale = 4096
+
+// pcm encodes samples as s16le PCM.
+func pcm(samples ...float64) []byte {
+	b := make([]byte, 0, 2*len(samples))
+	for _, s := range samples {
+		b = binary.LittleEndian.AppendUint16(b, uint16(int16(math.Round(s*sampleScale))))
+	}
+
+	return b
+}
+
+// fromPCM decodes s16le PCM.
+func fromPCM(b []byte) []float64 {
+	samples := make([]float64, 0, len(b)/2)
+	for i := 0; i+2 <= len(b); i += 2 {
+		samples = append(samples, float64(int16(binary.LittleEndian.Uint16(b[i:])))/sampleScale)
+	}
+
+	return samples
+}
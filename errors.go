@@ -0,0 +1,11 @@
+package apollo
+
+import (
+	"errors"
+)
+
+var ErrNotPlaying = errors.New("nothing is currently playing")
+var ErrSeekUnsupported = errors.New("codec does not support seeking")
+var ErrPlayerClosed = errors.New("player is closed")
+var ErrUnknownPlayable = errors.New("no resolver registered for playable type")
+var ErrUnknownCodec = errors.New("no codec registered with name")
@@ -0,0 +1,99 @@
+package apollo
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/eolso/threadsafe"
+)
+
+type EventType int
+
+func (e EventType) String() string {
+	return []string{
+		"TrackStarted", "TrackFinished", "TrackSkipped", "TrackFailed", "StateChanged", "QueueChanged",
+		"QueueExhausted", "MetadataChanged",
+	}[e]
+}
+
+const (
+	// TrackStarted is emitted once a Playable has been downloaded and opened, right before its first packet is sent.
+	TrackStarted EventType = iota
+	// TrackFinished is emitted when a Playable has been read to the end.
+	TrackFinished
+	// TrackSkipped is emitted when a Playable is stopped before reaching the end, e.g. by Next, Previous or Empty.
+	TrackSkipped
+	// TrackFailed is emitted when a Playable could not be downloaded, opened or read. PlayerEvent.Err holds the cause.
+	TrackFailed
+	// StateChanged is emitted whenever the PlayerState changes.
+	StateChanged
+	// QueueChanged is emitted whenever entries are added to, removed from or reordered in the queue.
+	QueueChanged
+	// QueueExhausted is emitted when a Playable ends and there is nothing left in the queue to play.
+	QueueExhausted
+	// MetadataChanged is emitted when the stream of the playing Playable reports new metadata, e.g. at the boundary
+	// between two links of a chained ogg stream.
+	MetadataChanged
+)
+
+// PlayerEvent describes something that happened inside a Player.
+type PlayerEvent struct {
+	Type EventType
+
+	// Playable is the Playable the event refers to. It is nil for events that aren't tied to a single Playable.
+	Playable Playable
+
+	// State is the PlayerState at the time of the event. For StateChanged events, PreviousState holds the state that
+	// was left.
+	State         PlayerState
+	PreviousState PlayerState
+
+	// Err is set on TrackFailed events.
+	Err error
+
+	Time time.Time
+}
+
+type eventBus struct {
+	nextId      atomic.Int64
+	subscribers *threadsafe.Map[int64, func(PlayerEvent)]
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: threadsafe.NewMap[int64, func(PlayerEvent)]()}
+}
+
+func (e *eventBus) subscribe(f func(PlayerEvent)) func() {
+	id := e.nextId.Add(1)
+	e.subscribers.Set(id, f)
+
+	return func() {
+		e.subscribers.Delete(id)
+	}
+}
+
+func (e *eventBus) publish(event PlayerEvent) {
+	for _, f := range e.subscribers.Values() {
+		f(event)
+	}
+}
+
+// Subscribe registers f to be called for every PlayerEvent. Handlers are called synchronously from the player's
+// goroutines, so they should return quickly and must not block. The returned function removes the subscription.
+func (p *Player) Subscribe(f func(PlayerEvent)) (unsubscribe func()) {
+	if f == nil {
+		return func() {}
+	}
+
+	return p.events.subscribe(f)
+}
+
+// emit fills in the common fields of event and publishes it to all subscribers.
+func (p *Player) emit(event PlayerEvent) {
+	if event.Type != StateChanged {
+		event.State = p.State()
+	}
+	event.Time = time.Now()
+
+	p.events.publish(event)
+}
@@ -0,0 +1,119 @@
+package apollo_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestPlayer_Subscribe(t *testing.T) {
+	type test struct {
+		delay        time.Duration
+		unsubscribed bool
+		expected     []string
+	}
+
+	events := []string{
+		"StateChanged Play", "TrackStarted a", "TrackFinished a", "TrackStarted b", "TrackFinished b",
+		"StateChanged Idle", "QueueExhausted",
+	}
+
+	tests := map[string]test{
+		"order":        {delay: 0, unsubscribed: false, expected: events},
+		"slow":         {delay: 10 * time.Millisecond, unsubscribed: false, expected: events},
+		"unsubscribed": {delay: 0, unsubscribed: true, expected: nil},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 4}, nil)
+			defer p.Close()
+			go func() {
+				for range p.Out() {
+				}
+			}()
+
+			p.Enqueue(testPlayable{name: "a", data: []byte("a")})
+			p.Enqueue(testPlayable{name: "b", data: []byte("b")})
+
+			exhausted := make(chan struct{})
+			p.Subscribe(func(e apollo.PlayerEvent) {
+				if e.Type == apollo.QueueExhausted {
+					close(exhausted)
+				}
+			})
+
+			rec := newRecorder()
+			unsubscribe := p.Subscribe(func(e apollo.PlayerEvent) {
+				time.Sleep(tst.delay)
+				rec.record(e)
+			})
+			if tst.unsubscribed {
+				unsubscribe()
+			}
+
+			p.Play()
+
+			select {
+			case <-exhausted:
+			case <-time.After(time.Second):
+				t.Fatal("expected the queue to be exhausted")
+			}
+
+			if got := rec.wait(len(tst.expected)); !reflect.DeepEqual(got, tst.expected) {
+				t.Fatalf("expected events %v; got %v", tst.expected, got)
+			}
+		})
+	}
+}
+
+// recorder records the PlayerEvents it is passed as strings of their type and either the name of their Playable or
+// their state.
+type recorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func newRecorder() *recorder {
+	return &recorder{}
+}
+
+func (r *recorder) record(e apollo.PlayerEvent) {
+	event := e.Type.String()
+	switch {
+	case e.Type == apollo.StateChanged:
+		event += " " + e.State.String()
+	case e.Playable != nil:
+		event += " " + e.Playable.Name()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// wait returns the recorded events once there are at least n of them, or after a second has passed. Events recorded
+// shortly after are included.
+func (r *recorder) wait(n int) []string {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		r.lock.Lock()
+		recorded := len(r.events)
+		r.lock.Unlock()
+
+		if recorded >= n {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]string(nil), r.events...)
+}
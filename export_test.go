@@ -0,0 +1,41 @@
+package apollo
+
+// This file exposes unexported parts of the package to its tests.
+
+func NewRamp(format PCMFormat, gain float64) *ramp {
+	return newRamp(format, gain)
+}
+
+func (r *ramp) Apply(p []byte, gain float64) {
+	r.apply(p, gain)
+}
+
+var TagLoudness = tagLoudness
+
+var NewLoudnessStore = newLoudnessStore
+
+func (s *loudnessStore) Get(key string) (Loudness, bool) {
+	return s.get(key)
+}
+
+func (s *loudnessStore) Set(key string, l Loudness) error {
+	return s.set(key, l)
+}
+
+var NormalizeContentType = normalizeContentType
+
+var NewPacer = newPacer
+
+var Pace = pace[[]byte]
+
+func (pc *pacer) Reset() {
+	pc.reset()
+}
+
+func (pc *pacer) Underruns() int64 {
+	return pc.underruns.Load()
+}
+
+func (pc *pacer) Overruns() int64 {
+	return pc.overruns.Load()
+}
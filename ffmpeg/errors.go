@@ -0,0 +1,86 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// maxStderr is how much of ffmpeg's stderr is kept for an ExitError. Older output is dropped first, since the last
+// lines usually hold the actual error.
+const maxStderr = 64 << 10
+
+// ExitError is returned by Process.Read when ffmpeg exits unsuccessfully, once its output has been read to the end.
+type ExitError struct {
+	// Code is the exit code, or -1 if the process was killed by a signal.
+	Code int
+	// Signal is the signal that killed the process, or nil if it exited on its own.
+	Signal os.Signal
+	// Stderr holds what the process wrote to stderr, without surrounding whitespace.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("ffmpeg exited with code %d", e.Code)
+	if e.Signal != nil {
+		msg = fmt.Sprintf("ffmpeg was killed by signal %s", e.Signal)
+	}
+
+	if len(e.Stderr) > 0 {
+		msg += ": " + string(e.Stderr)
+	}
+
+	return msg
+}
+
+// newExitError turns the error of exec.Cmd.Wait into an ExitError, or returns it as is if the process didn't get to
+// exit. nil is returned for a successful exit.
+func newExitError(err error, stderr *stderrBuffer) error {
+	if err == nil || err == exec.ErrWaitDelay {
+		return nil
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+
+	e := &ExitError{
+		Code:   exitErr.ExitCode(),
+		Stderr: bytes.TrimSpace(stderr.Bytes()),
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		e.Signal = status.Signal()
+	}
+
+	return e
+}
+
+// stderrBuffer keeps the last maxStderr bytes written to it. It is safe for concurrent use, since the process' stderr
+// is still copied into it while it is read.
+type stderrBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *stderrBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > maxStderr {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-maxStderr:]...)
+	}
+
+	return len(p), nil
+}
+
+func (b *stderrBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.Clone(b.buf)
+}
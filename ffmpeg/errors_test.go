@@ -0,0 +1,106 @@
+package ffmpeg_test
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/olympus-go/apollo/ffmpeg"
+)
+
+func TestNewExitError(t *testing.T) {
+	type test struct {
+		err      error
+		stderr   string
+		expected error
+		message  string
+	}
+
+	errOther := errors.New("other")
+
+	tests := map[string]test{
+		"success":    {err: nil, expected: nil},
+		"wait_delay": {err: exec.ErrWaitDelay, expected: nil},
+		"other":      {err: errOther, expected: errOther, message: "other"},
+		"exit_code": {
+			err:      run(t, "exit 3"),
+			stderr:   "  invalid input \n",
+			expected: &ffmpeg.ExitError{Code: 3, Stderr: []byte("invalid input")},
+			message:  "ffmpeg exited with code 3: invalid input",
+		},
+		"signal": {
+			err:      run(t, "kill -9 $$"),
+			expected: &ffmpeg.ExitError{Code: -1, Signal: syscall.SIGKILL},
+			message:  "ffmpeg was killed by signal killed",
+		},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			stderr := &ffmpeg.StderrBuffer{}
+			_, _ = stderr.Write([]byte(tst.stderr))
+
+			err := ffmpeg.NewExitError(tst.err, stderr)
+			if !reflect.DeepEqual(err, tst.expected) {
+				t.Fatalf("expected %#v; got %#v", tst.expected, err)
+			}
+			if err != nil && err.Error() != tst.message {
+				t.Fatalf("expected message %q; got %q", tst.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestStderrBuffer(t *testing.T) {
+	type test struct {
+		writes   [][]byte
+		expected []byte
+	}
+
+	half := bytes.Repeat([]byte("a"), ffmpeg.MaxStderr/2)
+	full := bytes.Repeat([]byte("b"), ffmpeg.MaxStderr)
+
+	tests := map[string]test{
+		"empty":    {writes: nil, expected: nil},
+		"small":    {writes: [][]byte{[]byte("abc"), []byte("def")}, expected: []byte("abcdef")},
+		"full":     {writes: [][]byte{half, half}, expected: append(append([]byte{}, half...), half...)},
+		"overflow": {writes: [][]byte{half, full}, expected: full},
+		"partial":  {writes: [][]byte{full, []byte("end")}, expected: append(append([]byte{}, full[3:]...), "end"...)},
+		"large":    {writes: [][]byte{append(append([]byte{}, half...), full...)}, expected: full},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := &ffmpeg.StderrBuffer{}
+			for _, p := range tst.writes {
+				if n, err := b.Write(p); n != len(p) || err != nil {
+					t.Fatalf("expected %d bytes written; got %d, %v", len(p), n, err)
+				}
+			}
+
+			if !bytes.Equal(b.Bytes(), tst.expected) {
+				t.Fatalf("expected %d bytes ending in %q; got %d bytes ending in %q", len(tst.expected),
+					tail(tst.expected), len(b.Bytes()), tail(b.Bytes()))
+			}
+		})
+	}
+}
+
+// run runs script with sh and returns the error of Wait.
+func run(t *testing.T, script string) error {
+	t.Helper()
+
+	cmd := exec.Command("sh", "-c", script)
+	if err := cmd.Start(); err != nil {
+		t.Skipf("can't run sh: %v", err)
+	}
+
+	return cmd.Wait()
+}
+
+func tail(b []byte) []byte {
+	return b[max(len(b)-8, 0):]
+}
@@ -0,0 +1,9 @@
+package ffmpeg
+
+// This file exposes unexported parts of the package to its tests.
+
+const MaxStderr = maxStderr
+
+type StderrBuffer = stderrBuffer
+
+var NewExitError = newExitError
@@ -0,0 +1,45 @@
+package formats
+
+const (
+	// ADTS is a stream of raw AAC frames with a small header each, which is what most internet radio serves.
+	ADTS = "adts"
+	// FragmentedMP4 is an mp4 container written in fragments, so that it can be written to a pipe.
+	FragmentedMP4 = "mp4"
+)
+
+// AACFormat contains additional ffmpeg fields specific to ffmpeg's native aac encoder. Fields left empty use ffmpeg's
+// defaults. The bitrate is set with ffmpeg.Options.Bitrate.
+type AACFormat struct {
+	Container string // ADTS or FragmentedMP4. Defaults to ADTS if empty.
+	Profile   string // Profile, e.g. "aac_low" or "aac_main" (-profile:a)
+}
+
+func (a AACFormat) Name() []string {
+	return []string{"-c:a", "aac"}
+}
+
+func (a AACFormat) Format() string {
+	if a.Container == "" {
+		return ADTS
+	}
+
+	return a.Container
+}
+
+func (a AACFormat) Args() []string {
+	args := make([]string, 0, 4)
+
+	if a.Profile != "" {
+		args = append(args, "-profile:a", a.Profile)
+	}
+	// A regular mp4 file needs to be rewritten once the stream ends, which a pipe doesn't allow.
+	if a.Format() == FragmentedMP4 {
+		args = append(args, "-movflags", "+frag_keyframe+empty_moov+default_base_moof")
+	}
+
+	return args
+}
+
+func (a AACFormat) InputArgs() []string {
+	return []string{"-f", a.Format()}
+}
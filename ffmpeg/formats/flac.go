@@ -0,0 +1,39 @@
+package formats
+
+import "strconv"
+
+// FLACFormat contains additional ffmpeg fields specific to ffmpeg's native flac encoder. Fields left empty use ffmpeg's
+// defaults. The compression level is set with ffmpeg.Options.CompressionLevel.
+type FLACFormat struct {
+	BitsPerSample int  // Bit depth of the samples, 16 or 24
+	ExactRice     bool // Compute exact rice parameters, which is slower but slightly smaller (-exact_rice_parameters)
+}
+
+func (f FLACFormat) Name() []string {
+	return []string{"-c:a", "flac"}
+}
+
+func (f FLACFormat) Format() string {
+	return "flac"
+}
+
+func (f FLACFormat) Args() []string {
+	args := make([]string, 0, 6)
+
+	switch f.BitsPerSample {
+	case 16:
+		args = append(args, "-sample_fmt", "s16")
+	case 24:
+		// flac has no 24-bit sample format of its own, so 32-bit samples are written with 24 significant bits.
+		args = append(args, "-sample_fmt", "s32", "-bits_per_raw_sample", strconv.Itoa(f.BitsPerSample))
+	}
+	if f.ExactRice {
+		args = append(args, "-exact_rice_parameters", "1")
+	}
+
+	return args
+}
+
+func (f FLACFormat) InputArgs() []string {
+	return []string{"-f", f.Format()}
+}
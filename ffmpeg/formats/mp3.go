@@ -0,0 +1,29 @@
+package formats
+
+// MP3Format contains additional ffmpeg fields specific to libmp3lame. Fields left empty use ffmpeg's defaults. A
+// constant bitrate is set with ffmpeg.Options.Bitrate, or a variable bitrate quality with ffmpeg.Options.Quality.
+type MP3Format struct {
+	JointStereo bool // Use joint stereo (-joint_stereo)
+}
+
+func (m MP3Format) Name() []string {
+	return []string{"-c:a", "libmp3lame"}
+}
+
+func (m MP3Format) Format() string {
+	return "mp3"
+}
+
+func (m MP3Format) Args() []string {
+	args := make([]string, 0, 2)
+
+	if m.JointStereo {
+		args = append(args, "-joint_stereo", "1")
+	}
+
+	return args
+}
+
+func (m MP3Format) InputArgs() []string {
+	return []string{"-f", m.Format()}
+}
@@ -36,3 +36,7 @@ func (o OpusFormat) Args() []string {
 		"-application", o.Application,
 	}
 }
+
+func (o OpusFormat) InputArgs() []string {
+	return []string{"-f", o.Format()}
+}
@@ -0,0 +1,52 @@
+package formats
+
+import "strconv"
+
+// PCMFormat contains additional ffmpeg fields for raw little endian PCM. It can be used as an Encoder to decode to PCM,
+// or as a Decoder to read PCM, e.g. for the codecs used with apollo.Player.SetEncoder.
+type PCMFormat struct {
+	SampleRate int // Sample rate (-ar). Left to ffmpeg if 0.
+	Channels   int // Number of channels (-ac). Left to ffmpeg if 0.
+	// SampleFormat is "s16le" or "f32le", matching apollo.SampleFormat. Defaults to "s16le" if empty.
+	SampleFormat string
+}
+
+// DefaultPCMFormat returns a PCMFormat matching apollo.DefaultPCMFormat.
+func DefaultPCMFormat() PCMFormat {
+	return PCMFormat{
+		SampleRate:   48000,
+		Channels:     2,
+		SampleFormat: "s16le",
+	}
+}
+
+func (p PCMFormat) Name() []string {
+	return []string{"-c:a", "pcm_" + p.Format()}
+}
+
+func (p PCMFormat) Format() string {
+	if p.SampleFormat == "" {
+		return "s16le"
+	}
+
+	return p.SampleFormat
+}
+
+func (p PCMFormat) Args() []string {
+	args := make([]string, 0, 4)
+
+	if p.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(p.SampleRate))
+	}
+	if p.Channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(p.Channels))
+	}
+
+	return args
+}
+
+// InputArgs includes the format itself, since raw PCM input can't be probed. The codec name is left out, since the
+// format already determines it.
+func (p PCMFormat) InputArgs() []string {
+	return append([]string{"-f", p.Format()}, p.Args()...)
+}
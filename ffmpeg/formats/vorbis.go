@@ -0,0 +1,36 @@
+package formats
+
+// VorbisFormat contains additional ffmpeg fields specific to libvorbis. Fields left empty use ffmpeg's defaults.
+type VorbisFormat struct {
+	Quality    string // Variable bitrate quality from -1 to 10 (-q:a)
+	MinBitrate string // Minimum bitrate when managing the bitrate, e.g. "96k" (-minrate)
+	MaxBitrate string // Maximum bitrate when managing the bitrate, e.g. "256k" (-maxrate)
+}
+
+func (v VorbisFormat) Name() []string {
+	return []string{"-c:a", "libvorbis"}
+}
+
+func (v VorbisFormat) Format() string {
+	return "ogg"
+}
+
+func (v VorbisFormat) Args() []string {
+	args := make([]string, 0, 6)
+
+	if v.Quality != "" {
+		args = append(args, "-q:a", v.Quality)
+	}
+	if v.MinBitrate != "" {
+		args = append(args, "-minrate", v.MinBitrate)
+	}
+	if v.MaxBitrate != "" {
+		args = append(args, "-maxrate", v.MaxBitrate)
+	}
+
+	return args
+}
+
+func (v VorbisFormat) InputArgs() []string {
+	return []string{"-f", v.Format()}
+}
@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+
+	"github.com/olympus-go/apollo"
+)
+
+// loudnormStats contains the fields of the loudnorm filter's json output that are used.
+type loudnormStats struct {
+	InputI  string `json:"input_i"`
+	InputTP string `json:"input_tp"`
+}
+
+// MeasureLoudness runs the audio read from r through the loudnorm filter's analysis pass and returns the measured
+// integrated loudness and true peak. Instead of a second loudnorm pass, the measurement is meant to be applied as a
+// plain gain, which is what apollo.Player does when normalizing. It can be passed to Player.SetLoudnessAnalyzer.
+func MeasureLoudness(ctx context.Context, r io.Reader) (apollo.Loudness, error) {
+	var stderr bytes.Buffer
+
+	args := []string{"-hide_banner", "-nostats", "-i", Stdin, "-af", "loudnorm=print_format=json", "-f", "null", "-"}
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return apollo.Loudness{}, ctx.Err()
+		}
+		return apollo.Loudness{}, fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	// The json object is printed last, after the filter's log prefix.
+	out := stderr.Bytes()
+	start := bytes.LastIndexByte(out, '{')
+	end := bytes.LastIndexByte(out, '}')
+	if start < 0 || end < start {
+		return apollo.Loudness{}, errors.New("no loudnorm output found")
+	}
+
+	var stats loudnormStats
+	if err := json.Unmarshal(out[start:end+1], &stats); err != nil {
+		return apollo.Loudness{}, err
+	}
+
+	integrated, err := strconv.ParseFloat(stats.InputI, 64)
+	if err != nil || math.IsInf(integrated, 0) {
+		// Silence measures as -inf.
+		return apollo.Loudness{}, fmt.Errorf("invalid integrated loudness %q", stats.InputI)
+	}
+
+	l := apollo.Loudness{Integrated: integrated}
+	if tp, err := strconv.ParseFloat(stats.InputTP, 64); err == nil && !math.IsInf(tp, 0) {
+		l.Peak = math.Pow(10, tp/20)
+	}
+
+	return l, nil
+}
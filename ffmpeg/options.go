@@ -9,6 +9,12 @@ type Coder interface {
 	Args() []string
 }
 
+// InputCoder is implemented by Coders that take different arguments when used as Options.Decoder, e.g. to force the
+// demuxer of the input. InputArgs is then used in place of Name and Args.
+type InputCoder interface {
+	InputArgs() []string
+}
+
 type Options struct {
 	Decoder          Coder
 	Encoder          Coder
@@ -29,13 +35,16 @@ func (o Options) Args() []string {
 
 	args = append(args, "-hide_banner", "-loglevel", "error")
 
-	if o.Decoder != nil {
+	if input, ok := o.Decoder.(InputCoder); ok {
+		args = append(args, input.InputArgs()...)
+	} else if o.Decoder != nil {
 		args = append(args, o.Decoder.Name()...)
 		args = append(args, o.Decoder.Args()...)
 	}
 	args = append(args, "-i", o.Input)
 
-	if o.Input == Stdin {
+	// The output format can't be guessed from a pipe.
+	if o.Encoder != nil && (o.Input == Stdin || o.Output == Stdout) {
 		args = append(args, "-f", o.Encoder.Format())
 	}
 
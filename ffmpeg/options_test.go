@@ -0,0 +1,49 @@
+package ffmpeg_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/olympus-go/apollo/ffmpeg"
+	"github.com/olympus-go/apollo/ffmpeg/formats"
+)
+
+func TestOptions_Args(t *testing.T) {
+	type test struct {
+		options  ffmpeg.Options
+		expected []string
+	}
+
+	tests := map[string]test{
+		"pcm_encoder": {
+			options: ffmpeg.Options{Encoder: formats.DefaultPCMFormat(), Input: "in.mp3", Output: ffmpeg.Stdout},
+			expected: []string{"-hide_banner", "-loglevel", "error", "-i", "in.mp3", "-f", "s16le",
+				"-c:a", "pcm_s16le", "-ar", "48000", "-ac", "2", ffmpeg.Stdout},
+		},
+		"pcm_decoder": {
+			options: ffmpeg.Options{Decoder: formats.DefaultPCMFormat(), Encoder: formats.MP3Format{},
+				Input: ffmpeg.Stdin, Output: ffmpeg.Stdout},
+			expected: []string{"-hide_banner", "-loglevel", "error", "-f", "s16le", "-ar", "48000", "-ac", "2",
+				"-i", ffmpeg.Stdin, "-f", "mp3", "-c:a", "libmp3lame", ffmpeg.Stdout},
+		},
+		"pcm_zero": {
+			options: ffmpeg.Options{Encoder: formats.PCMFormat{SampleFormat: "f32le"}, Input: "in.mp3",
+				Output: ffmpeg.Stdout},
+			expected: []string{"-hide_banner", "-loglevel", "error", "-i", "in.mp3", "-f", "f32le", "-c:a", "pcm_f32le",
+				ffmpeg.Stdout},
+		},
+		"bitrate": {
+			options: ffmpeg.Options{Encoder: formats.AACFormat{}, Input: "in.flac", Output: "out.aac", Bitrate: "128k"},
+			expected: []string{"-hide_banner", "-loglevel", "error", "-i", "in.flac", "-c:a", "aac", "-b:a", "128k",
+				"out.aac"},
+		},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			if args := tst.options.Args(); !slices.Equal(args, tst.expected) {
+				t.Fatalf("expected %v; got %v", tst.expected, args)
+			}
+		})
+	}
+}
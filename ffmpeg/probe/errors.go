@@ -0,0 +1,7 @@
+package probe
+
+import (
+	"errors"
+)
+
+var ErrInvalidOutput = errors.New("invalid ffprobe output")
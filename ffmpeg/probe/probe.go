@@ -0,0 +1,260 @@
+// Package probe inspects media files with ffprobe. It doesn't depend on the rest of apollo, so that apollo itself can
+// use it.
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaInfo describes a media file as reported by ffprobe.
+type MediaInfo struct {
+	// Container holds the names of the container format, e.g. ["mov", "mp4", "m4a", "3gp", "3g2", "mj2"] for mp4 files.
+	Container []string
+	// Duration is 0 if it is unknown.
+	Duration time.Duration
+	// Bitrate is the overall bitrate in bits per second, or 0 if it is unknown.
+	Bitrate int
+	// Tags holds the tags of the container with lowercase keys, since their case differs between containers.
+	Tags     map[string]string
+	Streams  []Stream
+	Chapters []Chapter
+}
+
+// Stream describes a single stream of a media file.
+type Stream struct {
+	Index int
+	// Type is the kind of stream, e.g. "audio", "video" or "subtitle".
+	Type  string
+	Codec string
+	// SampleRate, Channels and ChannelLayout are only set for audio streams.
+	SampleRate    int
+	Channels      int
+	ChannelLayout string
+	// Bitrate is in bits per second, or 0 if it is unknown.
+	Bitrate  int
+	Duration time.Duration
+	// Tags holds the tags of the stream with lowercase keys.
+	Tags map[string]string
+	// CoverArt is set for streams that hold an attached picture rather than actual video.
+	CoverArt bool
+}
+
+type Chapter struct {
+	Start time.Duration
+	End   time.Duration
+	Title string
+	// Tags holds the tags of the chapter with lowercase keys.
+	Tags map[string]string
+}
+
+// Audio returns the first audio stream.
+func (m MediaInfo) Audio() (Stream, bool) {
+	for _, stream := range m.Streams {
+		if stream.Type == "audio" {
+			return stream, true
+		}
+	}
+
+	return Stream{}, false
+}
+
+// HasCoverArt reports whether the file has embedded cover art.
+func (m MediaInfo) HasCoverArt() bool {
+	return slices.ContainsFunc(m.Streams, func(s Stream) bool { return s.CoverArt })
+}
+
+// AllTags returns the tags of the container merged with those of its streams. Container tags take precedence, since
+// some containers only store tags on their streams.
+func (m MediaInfo) AllTags() map[string]string {
+	tags := make(map[string]string)
+
+	for _, stream := range m.Streams {
+		for k, v := range stream.Tags {
+			tags[k] = v
+		}
+	}
+
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+
+	return tags
+}
+
+// ContentType returns the MIME type of the file, including the audio codec for containers that can hold several, or ""
+// if it isn't a known audio format.
+func (m MediaInfo) ContentType() string {
+	codec := ""
+	if audio, ok := m.Audio(); ok {
+		codec = audio.Codec
+	}
+
+	switch {
+	case slices.Contains(m.Container, "ogg") && codec != "":
+		return "audio/ogg; codecs=" + codec
+	case slices.Contains(m.Container, "ogg"):
+		return "audio/ogg"
+	case slices.Contains(m.Container, "mp3"):
+		return "audio/mpeg"
+	case slices.Contains(m.Container, "flac"):
+		return "audio/flac"
+	case slices.Contains(m.Container, "wav"):
+		return "audio/wav"
+	case slices.Contains(m.Container, "aac"):
+		return "audio/aac"
+	case slices.Contains(m.Container, "mp4"):
+		return "audio/mp4"
+	case slices.Contains(m.Container, "webm") && codec != "":
+		return "audio/webm; codecs=" + codec
+	case slices.Contains(m.Container, "matroska"):
+		return "audio/x-matroska"
+	}
+
+	return ""
+}
+
+// File probes the media file at path.
+func File(ctx context.Context, path string) (MediaInfo, error) {
+	return run(ctx, path, nil)
+}
+
+// Reader probes the media read from r. Some formats can only be fully probed from a file, since ffprobe can't seek r.
+func Reader(ctx context.Context, r io.Reader) (MediaInfo, error) {
+	return run(ctx, "pipe:0", r)
+}
+
+func run(ctx context.Context, input string, r io.Reader) (MediaInfo, error) {
+	args := []string{
+		"-v", "error",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		"-print_format", "json=compact=1",
+		"-i", input,
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffprobe", args...)
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return MediaInfo{}, fmt.Errorf("ffprobe: %w: %s", err, msg)
+		}
+		return MediaInfo{}, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	return Parse(stdout.Bytes())
+}
+
+// output mirrors the parts of ffprobe's json output that are used. ffprobe prints most numbers as strings.
+type output struct {
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int               `json:"index"`
+		CodecName     string            `json:"codec_name"`
+		CodecType     string            `json:"codec_type"`
+		SampleRate    string            `json:"sample_rate"`
+		Channels      int               `json:"channels"`
+		ChannelLayout string            `json:"channel_layout"`
+		BitRate       string            `json:"bit_rate"`
+		Duration      string            `json:"duration"`
+		Tags          map[string]string `json:"tags"`
+		Disposition   struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+// Parse parses the json output of ffprobe run with -show_format, -show_streams and optionally -show_chapters.
+// ErrInvalidOutput is returned if data isn't such output.
+func Parse(data []byte) (MediaInfo, error) {
+	var out output
+	if err := json.Unmarshal(data, &out); err != nil || out.Format.FormatName == "" {
+		return MediaInfo{}, ErrInvalidOutput
+	}
+
+	info := MediaInfo{
+		Container: strings.Split(out.Format.FormatName, ","),
+		Duration:  parseSeconds(out.Format.Duration),
+		Bitrate:   parseInt(out.Format.BitRate),
+		Tags:      lowerKeys(out.Format.Tags),
+	}
+
+	for _, s := range out.Streams {
+		info.Streams = append(info.Streams, Stream{
+			Index:         s.Index,
+			Type:          s.CodecType,
+			Codec:         s.CodecName,
+			SampleRate:    parseInt(s.SampleRate),
+			Channels:      s.Channels,
+			ChannelLayout: s.ChannelLayout,
+			Bitrate:       parseInt(s.BitRate),
+			Duration:      parseSeconds(s.Duration),
+			Tags:          lowerKeys(s.Tags),
+			CoverArt:      s.Disposition.AttachedPic == 1,
+		})
+	}
+
+	for _, c := range out.Chapters {
+		tags := lowerKeys(c.Tags)
+		info.Chapters = append(info.Chapters, Chapter{
+			Start: parseSeconds(c.StartTime),
+			End:   parseSeconds(c.EndTime),
+			Title: tags["title"],
+			Tags:  tags,
+		})
+	}
+
+	return info, nil
+}
+
+// parseSeconds parses a duration in decimal seconds, returning 0 for values ffprobe doesn't know, e.g. "N/A".
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// parseInt parses an integer, returning 0 for values ffprobe doesn't know.
+func parseInt(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+
+	return i
+}
+
+func lowerKeys(tags map[string]string) map[string]string {
+	lower := make(map[string]string, len(tags))
+	for k, v := range tags {
+		lower[strings.ToLower(k)] = v
+	}
+
+	return lower
+}
@@ -0,0 +1,106 @@
+package probe_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo/ffmpeg/probe"
+)
+
+const mp3Output = `{"streams":[{"index":0,"codec_name":"mp3","codec_type":"audio","sample_rate":"44100","channels":2,` +
+	`"channel_layout":"stereo","bit_rate":"320000","duration":"215.510204","disposition":{"attached_pic":0}},` +
+	`{"index":1,"codec_name":"mjpeg","codec_type":"video","duration":"N/A","disposition":{"attached_pic":1},` +
+	`"tags":{"comment":"Cover (front)"}}],"chapters":[{"start_time":"0.000000","end_time":"90.500000",` +
+	`"tags":{"title":"Intro"}}],"format":{"format_name":"mp3","duration":"215.510204","bit_rate":"321456",` +
+	`"tags":{"TITLE":"Song","Artist":"Someone"}}}`
+
+func TestParse(t *testing.T) {
+	type test struct {
+		data    string
+		want    probe.MediaInfo
+		wantErr error
+	}
+
+	tests := map[string]test{
+		"mp3_with_cover": {
+			data: mp3Output,
+			want: probe.MediaInfo{
+				Container: []string{"mp3"},
+				Duration:  215510204 * time.Microsecond,
+				Bitrate:   321456,
+				Tags:      map[string]string{"title": "Song", "artist": "Someone"},
+				Streams: []probe.Stream{
+					{
+						Index:         0,
+						Type:          "audio",
+						Codec:         "mp3",
+						SampleRate:    44100,
+						Channels:      2,
+						ChannelLayout: "stereo",
+						Bitrate:       320000,
+						Duration:      215510204 * time.Microsecond,
+						Tags:          map[string]string{},
+					},
+					{
+						Index:    1,
+						Type:     "video",
+						Codec:    "mjpeg",
+						Tags:     map[string]string{"comment": "Cover (front)"},
+						CoverArt: true,
+					},
+				},
+				Chapters: []probe.Chapter{
+					{
+						Start: 0,
+						End:   90500 * time.Millisecond,
+						Title: "Intro",
+						Tags:  map[string]string{"title": "Intro"},
+					},
+				},
+			},
+		},
+		"no_format": {
+			data:    `{"streams":[]}`,
+			wantErr: probe.ErrInvalidOutput,
+		},
+		"not_json": {
+			data:    "Invalid data found when processing input",
+			wantErr: probe.ErrInvalidOutput,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := probe.Parse([]byte(tc.data))
+			if err != tc.wantErr {
+				t.Fatalf("expected %v error; got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v; got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMediaInfo(t *testing.T) {
+	info, err := probe.Parse([]byte(mp3Output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if audio, ok := info.Audio(); !ok || audio.Codec != "mp3" {
+		t.Errorf("expected mp3 audio stream; got %+v", audio)
+	}
+	if !info.HasCoverArt() {
+		t.Error("expected cover art")
+	}
+	if got := info.ContentType(); got != "audio/mpeg" {
+		t.Errorf("expected content type audio/mpeg; got %s", got)
+	}
+
+	want := map[string]string{"title": "Song", "artist": "Someone", "comment": "Cover (front)"}
+	if got := info.AllTags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected tags %v; got %v", want, got)
+	}
+}
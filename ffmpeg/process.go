@@ -1,21 +1,35 @@
 package ffmpeg
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"strconv"
+	"time"
 
 	"github.com/olympus-go/apollo"
 )
 
+// waitDelay bounds how long a Process waits for the copying of its stdin and stderr to finish once ffmpeg has exited,
+// since the stdin source may be blocked in a read that never returns.
+const waitDelay = time.Second
+
 type Process struct {
-	r      io.ReadCloser
+	r      *os.File
 	codec  apollo.Codec
 	opts   Options
-	err    error
+	offset time.Duration
 	cancel context.CancelFunc
+
+	exit *exit
+}
+
+// exit holds the outcome of a single ffmpeg run. err is set before done is closed, and must only be read after.
+type exit struct {
+	done chan struct{}
+	err  error
 }
 
 func Version() string {
@@ -46,60 +60,151 @@ func (p *Process) WithCodec(codec apollo.Codec) *Process {
 }
 
 func (p *Process) Open(r io.Reader) error {
-	var stderr bytes.Buffer
-	var ctx context.Context
-	var err error
+	p.offset = 0
+	return p.start(r, p.opts)
+}
 
+// OpenAt starts a new ffmpeg process like Open, but overrides Options.StartTime so that the output begins at offset.
+func (p *Process) OpenAt(r io.Reader, offset time.Duration) error {
+	opts := p.opts
+	if offset > 0 {
+		opts.StartTime = strconv.FormatFloat(offset.Seconds(), 'f', 3, 64)
+	}
+	p.offset = offset
+
+	return p.start(r, opts)
+}
+
+func (p *Process) start(r io.Reader, opts Options) error {
+	var ctx context.Context
 	ctx, p.cancel = context.WithCancel(context.Background())
-	cmd := exec.CommandContext(ctx, "ffmpeg", p.opts.Args()...)
 
-	p.r, err = cmd.StdoutPipe()
+	cmd := exec.CommandContext(ctx, "ffmpeg", opts.Args()...)
+	cmd.WaitDelay = waitDelay
+
+	// stdout is a pipe of its own instead of cmd.StdoutPipe, which Wait would close before everything has been read.
+	pr, pw, err := os.Pipe()
 	if err != nil {
+		p.cancel()
 		return err
 	}
 
+	stderr := &stderrBuffer{}
+	cmd.Stdin = r
+	cmd.Stdout = pw
+	cmd.Stderr = stderr
+
+	err = cmd.Start()
+	// The child holds its own copy of the write end, so that stdout reaches EOF once it exits.
+	_ = pw.Close()
+	if err != nil {
+		_ = pr.Close()
+		p.cancel()
+		return err
+	}
+
+	e := &exit{done: make(chan struct{})}
+	p.r = pr
+	p.exit = e
+
+	go func() {
+		e.err = newExitError(cmd.Wait(), stderr)
+		close(e.done)
+	}()
+
 	if p.codec != nil {
 		if err = p.codec.Open(p.r); err != nil {
+			err = p.failure(err)
+			_ = p.Close()
 			return err
 		}
 	}
 
-	cmd.Stdin = r
-	cmd.Stderr = &stderr
+	return nil
+}
+
+// Read reads the processed output. Once the output ends, the process is waited for, and an *ExitError is returned
+// instead of io.EOF if it didn't exit successfully.
+func (p *Process) Read(b []byte) (int, error) {
+	var n int
+	var err error
+
+	if p.codec != nil {
+		n, err = p.codec.Read(b)
+	} else {
+		n, err = p.r.Read(b)
+	}
+
+	if err != nil {
+		err = p.failure(err)
+	}
+
+	return n, err
+}
 
-	// Start the process
-	if err = cmd.Start(); err != nil {
+// failure returns the ExitError of the process in place of err, if there is one. Errors caused by the output ending
+// wait for the process to exit first, while other errors only take an exit that already happened into account.
+func (p *Process) failure(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		<-p.exit.done
+	} else if !isDone(p.exit.done) {
 		return err
 	}
 
-	// Wait for the process to end naturally. If it doesn't exit 0, send the error + stderr on the errors channel.
-	go func() {
-		state, _ := cmd.Process.Wait()
-		if state.ExitCode() != 0 && state.String() != "signal: killed" {
-			p.err = fmt.Errorf("%s: %s", state.String(), stderr.Bytes())
-		}
-	}()
+	if p.exit.err != nil {
+		return p.exit.err
+	}
 
-	return nil
+	return err
 }
 
-func (p *Process) Read(b []byte) (int, error) {
-	if p.err != nil {
-		return 0, p.err
+// Position returns the position reported by the additional codec, shifted by the offset passed to OpenAt. The position
+// is only known if the additional codec implements apollo.TimedCodec.
+func (p *Process) Position() (time.Duration, bool) {
+	codec, ok := p.codec.(apollo.TimedCodec)
+	if !ok {
+		return 0, false
 	}
 
-	if p.codec != nil {
-		return p.codec.Read(b)
+	position, ok := codec.Position()
+	if !ok {
+		return 0, false
 	}
 
-	return p.r.Read(b)
+	return p.offset + position, true
 }
 
+// MetadataChanged forwards to the additional codec, if it implements apollo.MetadataCodec.
+func (p *Process) MetadataChanged() bool {
+	codec, ok := p.codec.(apollo.MetadataCodec)
+
+	return ok && codec.MetadataChanged()
+}
+
+// Close stops the process if it is still running and waits for it to exit.
 func (p *Process) Close() error {
 	if p.codec != nil {
 		p.codec.Close()
 	}
+
+	if p.r == nil {
+		return nil
+	}
+
 	p.cancel()
-	p.err = nil
-	return p.r.Close()
+	err := p.r.Close()
+	<-p.exit.done
+	p.r = nil
+
+	return err
+}
+
+// isDone reports whether done has been closed.
+func isDone(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
 }
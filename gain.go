@@ -0,0 +1,62 @@
+package apollo
+
+import (
+	"math"
+)
+
+// DecibelsToLinear converts a gain in dB to the factor samples are multiplied by.
+func DecibelsToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// ApplyGain multiplies every s16le sample in p by gain, clipping samples that go out of range. Use
+// SampleFormat.ApplyGain for other sample formats.
+func ApplyGain(p []byte, gain float64) {
+	S16LE.ApplyGain(p, gain)
+}
+
+// ApplyGain multiplies every sample in p by gain. Integer samples that go out of range are clipped.
+func (s SampleFormat) ApplyGain(p []byte, gain float64) {
+	if gain == 1 {
+		return
+	}
+
+	size := s.Size()
+	for i := 0; i+size <= len(p); i += size {
+		s.store(p[i:], s.load(p[i:])*gain)
+	}
+}
+
+// ramp applies a gain that can change at any time. Changes are spread over the next buffer passed to apply, so that
+// they don't cause audible clicks.
+type ramp struct {
+	format  PCMFormat
+	current float64
+}
+
+func newRamp(format PCMFormat, gain float64) *ramp {
+	return &ramp{
+		format:  format,
+		current: gain,
+	}
+}
+
+// apply multiplies the samples in p by gain, moving over from the previously applied gain if it differs.
+func (r *ramp) apply(p []byte, gain float64) {
+	if gain == r.current {
+		r.format.SampleFormat.ApplyGain(p, gain)
+		return
+	}
+
+	align := r.format.BlockAlign()
+	blocks := len(p) / align
+
+	for b := 0; b < blocks; b++ {
+		g := r.current + (gain-r.current)*float64(b+1)/float64(blocks)
+		r.format.SampleFormat.ApplyGain(p[b*align:(b+1)*align], g)
+	}
+
+	if blocks > 0 {
+		r.current = gain
+	}
+}
@@ -0,0 +1,134 @@
+package apollo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestDecibelsToLinear(t *testing.T) {
+	type test struct {
+		db       float64
+		expected float64
+	}
+
+	tests := map[string]test{
+		"unity":  {0, 1},
+		"double": {20 * math.Log10(2), 2},
+		"tenth":  {-20, 0.1},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			if gain := apollo.DecibelsToLinear(tst.db); math.Abs(gain-tst.expected) > 1e-9 {
+				t.Fatalf("expected %v; got %v", tst.expected, gain)
+			}
+		})
+	}
+}
+
+func TestApplyGain(t *testing.T) {
+	type test struct {
+		samples  []int16
+		gain     float64
+		expected []int16
+	}
+
+	tests := map[string]test{
+		"unity":    {[]int16{1000, -1000}, 1, []int16{1000, -1000}},
+		"half":     {[]int16{1000, -1000}, 0.5, []int16{500, -500}},
+		"clip":     {[]int16{20000, -20000}, 2, []int16{math.MaxInt16, math.MinInt16}},
+		"silence":  {[]int16{1000, -1000}, 0, []int16{0, 0}},
+		"inverted": {[]int16{1000}, -1, []int16{-1000}},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := s16(tst.samples...)
+			apollo.ApplyGain(p, tst.gain)
+
+			if expected := s16(tst.expected...); !bytes.Equal(p, expected) {
+				t.Fatalf("expected %v; got %v", expected, p)
+			}
+		})
+	}
+}
+
+func TestRamp(t *testing.T) {
+	type test struct {
+		start float64
+		// gains are applied to consecutive buffers of four samples of 1.
+		gains    []float64
+		expected [][]float64
+	}
+
+	tests := map[string]test{
+		"constant": {
+			start:    0.5,
+			gains:    []float64{0.5},
+			expected: [][]float64{{0.5, 0.5, 0.5, 0.5}},
+		},
+		"down": {
+			start:    1,
+			gains:    []float64{0, 0},
+			expected: [][]float64{{0.75, 0.5, 0.25, 0}, {0, 0, 0, 0}},
+		},
+		"up": {
+			start:    0,
+			gains:    []float64{1, 1},
+			expected: [][]float64{{0.25, 0.5, 0.75, 1}, {1, 1, 1, 1}},
+		},
+		"change_twice": {
+			start:    1,
+			gains:    []float64{0, 1},
+			expected: [][]float64{{0.75, 0.5, 0.25, 0}, {0.25, 0.5, 0.75, 1}},
+		},
+	}
+
+	format := apollo.PCMFormat{SampleRate: 4, Channels: 1}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := apollo.NewRamp(format, tst.start)
+
+			for i, gain := range tst.gains {
+				p := pcm(1, 1, 1, 1)
+				r.Apply(p, gain)
+
+				samples := fromPCM(p)
+				for j := range samples {
+					if math.Abs(samples[j]-tst.expected[i][j]) > 1e-3 {
+						t.Fatalf("expected buffer %d to be %v; got %v", i, tst.expected[i], samples)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestRamp_Empty checks that a gain change isn't lost when it is applied to an empty buffer.
+func TestRamp_Empty(t *testing.T) {
+	format := apollo.PCMFormat{SampleRate: 4, Channels: 1}
+	r := apollo.NewRamp(format, 1)
+
+	r.Apply(nil, 0)
+
+	p := pcm(1, 1, 1, 1)
+	r.Apply(p, 0)
+	if samples := fromPCM(p); samples[0] != 0.75 {
+		t.Fatalf("expected the ramp to start at 1; got %v", samples)
+	}
+}
+
+// s16 encodes samples as s16le PCM.
+func s16(samples ...int16) []byte {
+	b := make([]byte, 0, 2*len(samples))
+	for _, s := range samples {
+		b = binary.LittleEndian.AppendUint16(b, uint16(s))
+	}
+
+	return b
+}
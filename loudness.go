@@ -0,0 +1,246 @@
+package apollo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultTargetLoudness is the integrated loudness in LUFS that Playables are normalized to if
+// PlayerConfig.TargetLoudness is unset.
+const DefaultTargetLoudness = -14.0
+
+// Loudness describes how loud a Playable is.
+type Loudness struct {
+	// Integrated is the integrated loudness in LUFS.
+	Integrated float64 `json:"integrated"`
+	// Peak is the highest sample value relative to full scale, where 1 is full scale. It is 0 if unknown.
+	Peak float64 `json:"peak"`
+}
+
+// Gain returns the gain in dB that brings l to target LUFS. The gain is limited so that the peak doesn't exceed full
+// scale, if the peak is known.
+func (l Loudness) Gain(target float64) float64 {
+	gain := target - l.Integrated
+	if l.Peak > 0 {
+		gain = min(gain, -20*math.Log10(l.Peak))
+	}
+
+	return gain
+}
+
+// LoudnessProvider is implemented by Playables that know their loudness without it having to be measured, e.g. from
+// ReplayGain tags. The loudness may only become known once the Playable has been downloaded.
+type LoudnessProvider interface {
+	Loudness() (Loudness, bool)
+}
+
+// LoudnessAnalyzer measures the loudness of the audio read from r. See ffmpeg.MeasureLoudness.
+type LoudnessAnalyzer func(ctx context.Context, r io.Reader) (Loudness, error)
+
+// loudnessStore keeps known loudness values by Playable. If path is set, values are also stored there as JSON so that
+// they survive restarts.
+type loudnessStore struct {
+	path string
+
+	lock   sync.Mutex
+	values map[string]Loudness
+}
+
+func newLoudnessStore(path string) *loudnessStore {
+	s := &loudnessStore{
+		path:   path,
+		values: make(map[string]Loudness),
+	}
+
+	if path == "" {
+		return s
+	}
+
+	// A missing or unreadable file only means that values have to be measured again.
+	if b, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(b, &s.values)
+	}
+
+	return s
+}
+
+func (s *loudnessStore) get(key string) (Loudness, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	l, ok := s.values[key]
+	return l, ok
+}
+
+func (s *loudnessStore) set(key string, l Loudness) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.values[key] = l
+
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".part"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// SetLoudnessAnalyzer sets the function used to measure the loudness of Playables that don't provide it themselves,
+// when normalization is enabled. Measuring requires an extra download and runs in the background, so a Playable is
+// played unchanged until it has been measured. Only Identifiable Playables are measured, since the result is kept by
+// their identity.
+func (p *Player) SetLoudnessAnalyzer(a LoudnessAnalyzer) {
+	p.analyzer = a
+}
+
+// normalizationGain returns the gain in dB that normalizes playable, or 0 if normalization is disabled or the loudness
+// of playable isn't known yet. Unknown loudness is measured in the background, so that it is known next time.
+func (p *Player) normalizationGain(playable Playable) float64 {
+	if !p.config.Normalize || p.pipe == nil {
+		return 0
+	}
+
+	l, ok := p.loudness(playable)
+	if !ok {
+		p.measure(playable)
+		return 0
+	}
+
+	target := p.config.TargetLoudness
+	if target == 0 {
+		target = DefaultTargetLoudness
+	}
+
+	return l.Gain(target)
+}
+
+// loudness looks up the loudness of playable in the store, then asks the Playable itself. Values provided by
+// Identifiable Playables are stored.
+func (p *Player) loudness(playable Playable) (Loudness, bool) {
+	key, identifiable := loudnessKey(playable)
+	if identifiable {
+		if l, ok := p.loudnessStore.get(key); ok {
+			return l, true
+		}
+	}
+
+	provider, ok := playable.(LoudnessProvider)
+	if !ok {
+		return Loudness{}, false
+	}
+
+	l, ok := provider.Loudness()
+	if ok && identifiable {
+		p.storeLoudness(playable, key, l)
+	}
+
+	return l, ok
+}
+
+// measure starts measuring the loudness of playable with the analyzer in the background, unless it is already being
+// measured. The result is stored for the next time playable is played.
+func (p *Player) measure(playable Playable) {
+	key, identifiable := loudnessKey(playable)
+	if p.analyzer == nil || !identifiable {
+		return
+	}
+
+	if _, measuring := p.measuring.LoadOrStore(key, struct{}{}); measuring {
+		return
+	}
+
+	go func() {
+		defer p.measuring.Delete(key)
+
+		l, err := p.analyze(playable)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				p.logger.Error("failed to measure loudness",
+					slog.String("error", err.Error()),
+					slog.Any("playable", nameArtistAlbumType(playable)),
+				)
+			}
+			return
+		}
+
+		p.storeLoudness(playable, key, l)
+	}()
+}
+
+func (p *Player) storeLoudness(playable Playable, key string, l Loudness) {
+	if err := p.loudnessStore.set(key, l); err != nil {
+		p.logger.Error("failed to store loudness",
+			slog.String("error", err.Error()),
+			slog.Any("playable", nameArtistAlbumType(playable)),
+		)
+	}
+}
+
+// loudnessKey returns the key the loudness of playable is stored under. ok is false if playable isn't Identifiable.
+func loudnessKey(playable Playable) (key string, ok bool) {
+	identifiable, ok := playable.(Identifiable)
+	if !ok {
+		return "", false
+	}
+
+	return playable.Type() + ":" + identifiable.Id(), true
+}
+
+// analyze downloads playable and measures its loudness.
+func (p *Player) analyze(playable Playable) (Loudness, error) {
+	r, err := p.download(playable)
+	if err != nil {
+		return Loudness{}, err
+	}
+	defer r.Close()
+
+	return p.analyzer(p.ctx, r)
+}
+
+// tagLoudness reads the loudness from ReplayGain or R128 tags, as found in tags with lowercase keys.
+func tagLoudness(tags map[string]string) (Loudness, bool) {
+	if v, ok := tags["replaygain_track_gain"]; ok {
+		v = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.ToLower(v)), "db"))
+		if gain, err := strconv.ParseFloat(v, 64); err == nil {
+			// ReplayGain 2 uses a reference level of -18 LUFS.
+			l := Loudness{Integrated: -18 - gain}
+			if peak, err := strconv.ParseFloat(strings.TrimSpace(tags["replaygain_track_peak"]), 64); err == nil {
+				l.Peak = peak
+			}
+
+			return l, true
+		}
+	}
+
+	if v, ok := tags["r128_track_gain"]; ok {
+		// R128 gain is stored as a Q7.8 fixed point number relative to -23 LUFS.
+		if gain, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return Loudness{Integrated: -23 - float64(gain)/256}, true
+		}
+	}
+
+	return Loudness{}, false
+}
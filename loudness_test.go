@@ -0,0 +1,145 @@
+package apollo_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestLoudness_Gain(t *testing.T) {
+	type test struct {
+		loudness apollo.Loudness
+		target   float64
+		expected float64
+	}
+
+	tests := map[string]test{
+		"quieter":      {loudness: apollo.Loudness{Integrated: -8, Peak: 0.5}, target: -14, expected: -6},
+		"louder":       {loudness: apollo.Loudness{Integrated: -20, Peak: 0.5}, target: -14, expected: 6},
+		"other_target": {loudness: apollo.Loudness{Integrated: -20, Peak: 0.1}, target: -18, expected: 2},
+		// A peak of half of full scale leaves room for 6dB.
+		"clamped":      {loudness: apollo.Loudness{Integrated: -24, Peak: 0.5}, target: -14, expected: 6.0206},
+		"unknown_peak": {loudness: apollo.Loudness{Integrated: -24}, target: -14, expected: 10},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tst.loudness.Gain(tst.target); math.Abs(got-tst.expected) > 1e-4 {
+				t.Fatalf("expected gain %v dB; got %v dB", tst.expected, got)
+			}
+		})
+	}
+}
+
+func TestTagLoudness(t *testing.T) {
+	type test struct {
+		tags     map[string]string
+		expected apollo.Loudness
+		ok       bool
+	}
+
+	tests := map[string]test{
+		"replaygain": {
+			tags:     map[string]string{"replaygain_track_gain": "-6.50 dB", "replaygain_track_peak": "0.988"},
+			expected: apollo.Loudness{Integrated: -11.5, Peak: 0.988},
+			ok:       true,
+		},
+		"replaygain_no_peak": {
+			tags:     map[string]string{"replaygain_track_gain": "+2 db"},
+			expected: apollo.Loudness{Integrated: -20},
+			ok:       true,
+		},
+		"r128": {
+			tags:     map[string]string{"r128_track_gain": "-512"},
+			expected: apollo.Loudness{Integrated: -21},
+			ok:       true,
+		},
+		"replaygain_first": {
+			tags:     map[string]string{"replaygain_track_gain": "-5 dB", "r128_track_gain": "-512"},
+			expected: apollo.Loudness{Integrated: -13},
+			ok:       true,
+		},
+		"invalid_replaygain": {
+			tags:     map[string]string{"replaygain_track_gain": "loud", "r128_track_gain": "256"},
+			expected: apollo.Loudness{Integrated: -24},
+			ok:       true,
+		},
+		"invalid_r128": {tags: map[string]string{"r128_track_gain": "-2.5"}, ok: false},
+		"missing":      {tags: map[string]string{"title": "Song"}, ok: false},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			l, ok := apollo.TagLoudness(tst.tags)
+			if ok != tst.ok {
+				t.Fatalf("expected ok to be %t; got %t", tst.ok, ok)
+			}
+			if l != tst.expected {
+				t.Fatalf("expected loudness %+v; got %+v", tst.expected, l)
+			}
+		})
+	}
+}
+
+func TestLoudnessStore(t *testing.T) {
+	type test struct {
+		file     string
+		reopen   bool
+		expected bool
+	}
+
+	tests := map[string]test{
+		"memory":    {file: "", reopen: false, expected: true},
+		"persisted": {file: "loudness.json", reopen: true, expected: true},
+		"unstored":  {file: "", reopen: true, expected: false},
+		"nested":    {file: filepath.Join("a", "b", "loudness.json"), reopen: true, expected: true},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := ""
+			if tst.file != "" {
+				path = filepath.Join(t.TempDir(), tst.file)
+			}
+
+			l := apollo.Loudness{Integrated: -9.5, Peak: 0.75}
+			s := apollo.NewLoudnessStore(path)
+			if err := s.Set("test:a", l); err != nil {
+				t.Fatal(err)
+			}
+
+			if tst.reopen {
+				s = apollo.NewLoudnessStore(path)
+			}
+
+			got, ok := s.Get("test:a")
+			if ok != tst.expected {
+				t.Fatalf("expected ok to be %t; got %t", tst.expected, ok)
+			}
+			if ok && got != l {
+				t.Fatalf("expected loudness %+v; got %+v", l, got)
+			}
+			if _, ok = s.Get("test:b"); ok {
+				t.Fatal("expected no loudness for an unknown key")
+			}
+		})
+	}
+}
+
+func TestLoudnessStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loudness.json")
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := apollo.NewLoudnessStore(path)
+	if _, ok := s.Get("test:a"); ok {
+		t.Fatal("expected no loudness from a corrupt file")
+	}
+	if err := s.Set("test:a", apollo.Loudness{Integrated: -9}); err != nil {
+		t.Fatalf("expected a corrupt file to be overwritten; got %v", err)
+	}
+}
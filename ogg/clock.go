@@ -0,0 +1,61 @@
+package ogg
+
+import (
+	"bytes"
+	"time"
+)
+
+// clock converts granule positions of a logical bitstream into time. The meaning of a granule position depends on the
+// codec, so a clock can only be created for codecs whose identification header is recognized.
+type clock struct {
+	codec   string
+	rate    int64
+	preSkip int64
+	// gain is the output gain in dB of opus streams.
+	gain float64
+	// headers is the number of header packets the codec starts its stream with.
+	headers int
+}
+
+// detectClock inspects the first packet of a logical bitstream and returns a clock for it. Opus and Vorbis streams are
+// supported.
+func detectClock(packet []byte) (clock, bool) {
+	switch {
+	case len(packet) >= 19 && bytes.HasPrefix(packet, []byte("OpusHead")):
+		// Opus granule positions always count 48kHz samples, regardless of the input sample rate.
+		return clock{
+			codec:   "opus",
+			rate:    48000,
+			preSkip: int64(ByteOrder.Uint16(packet[10:12])),
+			// The output gain is a Q7.8 fixed point number of dB.
+			gain:    float64(int16(ByteOrder.Uint16(packet[16:18]))) / 256,
+			headers: 2,
+		}, true
+	case len(packet) >= 30 && packet[0] == 0x01 && bytes.Equal(packet[1:7], []byte("vorbis")):
+		rate := int64(ByteOrder.Uint32(packet[12:16]))
+		if rate == 0 {
+			return clock{}, false
+		}
+		return clock{codec: "vorbis", rate: rate, headers: 3}, true
+	}
+
+	return clock{}, false
+}
+
+// Duration returns the time represented by granule.
+func (c clock) Duration(granule int64) time.Duration {
+	if c.rate == 0 || granule <= c.preSkip {
+		return 0
+	}
+
+	return time.Duration((granule - c.preSkip) * int64(time.Second) / c.rate)
+}
+
+// Granule returns the granule position at which d is reached.
+func (c clock) Granule(d time.Duration) int64 {
+	if d <= 0 {
+		return c.preSkip
+	}
+
+	return c.preSkip + int64(d)*c.rate/int64(time.Second)
+}
@@ -0,0 +1,91 @@
+// Package comment parses vorbis comments, the tag format shared by the comment headers of ogg vorbis and ogg opus
+// streams.
+package comment
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid vorbis comment")
+
+// Header holds the vorbis comments of a stream.
+type Header struct {
+	Vendor string
+	// Comments holds the user comments in their original "KEY=value" form.
+	Comments []string
+}
+
+// Get returns the value of the first comment with the given key. Keys are compared case-insensitively.
+func (h Header) Get(key string) (string, bool) {
+	for _, comment := range h.Comments {
+		if k, v, ok := strings.Cut(comment, "="); ok && strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// Map returns the comments keyed by their lowercase keys. Values of repeated keys are joined by semicolons.
+func (h Header) Map() map[string]string {
+	m := make(map[string]string, len(h.Comments))
+	for _, comment := range h.Comments {
+		k, v, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+
+		k = strings.ToLower(k)
+		if prev, ok := m[k]; ok {
+			v = prev + ";" + v
+		}
+		m[k] = v
+	}
+
+	return m
+}
+
+// Parse parses the vendor string and user comments at the start of b, which is what follows the magic signature of a
+// comment header. The rest of b is returned, since codecs differ in what comes after the comments.
+func Parse(b []byte) (Header, []byte, error) {
+	vendor, b, ok := readString(b)
+	if !ok || len(b) < 4 {
+		return Header{}, nil, ErrInvalid
+	}
+
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	// Every comment takes at least 4 bytes, which bounds the allocation for corrupt counts.
+	if uint64(count)*4 > uint64(len(b)) {
+		return Header{}, nil, ErrInvalid
+	}
+
+	h := Header{Vendor: vendor, Comments: make([]string, 0, count)}
+	for i := uint32(0); i < count; i++ {
+		var comment string
+		if comment, b, ok = readString(b); !ok {
+			return Header{}, nil, ErrInvalid
+		}
+		h.Comments = append(h.Comments, comment)
+	}
+
+	return h, b, nil
+}
+
+// readString reads a length prefixed string from b and returns the rest of b.
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 4 {
+		return "", nil, false
+	}
+
+	n := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+	if uint64(n) > uint64(len(b)) {
+		return "", nil, false
+	}
+
+	return string(b[:n]), b[n:], true
+}
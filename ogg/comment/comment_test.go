@@ -0,0 +1,78 @@
+package comment_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/olympus-go/apollo/ogg/comment"
+)
+
+func TestParse(t *testing.T) {
+	type test struct {
+		data    []byte
+		header  comment.Header
+		rest    []byte
+		wantErr error
+	}
+
+	valid := genComments("vendor", "TITLE=Song", "artist=Someone")
+	hugeCount := genComments("vendor")
+	binary.LittleEndian.PutUint32(hugeCount[len(hugeCount)-4:], 1<<30)
+
+	tests := map[string]test{
+		"valid": {
+			data:   valid,
+			header: comment.Header{Vendor: "vendor", Comments: []string{"TITLE=Song", "artist=Someone"}},
+			rest:   []byte{},
+		},
+		"trailing_data": {
+			data:   append(genComments("vendor"), 1),
+			header: comment.Header{Vendor: "vendor", Comments: []string{}},
+			rest:   []byte{1},
+		},
+		"truncated":  {data: valid[:len(valid)-1], wantErr: comment.ErrInvalid},
+		"huge_count": {data: hugeCount, wantErr: comment.ErrInvalid},
+		"empty":      {data: nil, wantErr: comment.ErrInvalid},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			header, rest, err := comment.Parse(tst.data)
+			if err != tst.wantErr {
+				t.Fatalf("expected %v error; got %v", tst.wantErr, err)
+			}
+			if !reflect.DeepEqual(header, tst.header) {
+				t.Fatalf("expected %+v; got %+v", tst.header, header)
+			}
+			if !reflect.DeepEqual(rest, tst.rest) {
+				t.Fatalf("expected rest %v; got %v", tst.rest, rest)
+			}
+		})
+	}
+}
+
+func TestHeader_Map(t *testing.T) {
+	h := comment.Header{Comments: []string{"TITLE=Song", "ARTIST=First", "artist=Second", "invalid"}}
+
+	want := map[string]string{"title": "Song", "artist": "First;Second"}
+	if got := h.Map(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v; got %v", want, got)
+	}
+
+	if v, ok := h.Get("Artist"); !ok || v != "First" {
+		t.Fatalf("expected First; got %q", v)
+	}
+}
+
+func genComments(vendor string, comments ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(vendor)))
+	b = append(b, vendor...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, c := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+
+	return b
+}
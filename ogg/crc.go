@@ -0,0 +1,32 @@
+package ogg
+
+// crcPolynomial is the generator polynomial of the CRC-32 used by ogg. Unlike the common IEEE CRC-32, bits are
+// processed most significant first, and there is neither an initial value nor a final xor.
+const crcPolynomial uint32 = 0x04c11db7
+
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ crcPolynomial
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+
+	return table
+}()
+
+// crcUpdate returns crc extended by the bytes in p.
+func crcUpdate(crc uint32, p []byte) uint32 {
+	for _, b := range p {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+
+	return crc
+}
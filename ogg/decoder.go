@@ -1,14 +1,53 @@
 package ogg
 
 import (
-	"bytes"
 	"io"
+	"time"
 )
 
 type Decoder struct {
 	r           io.Reader
+	mode        Mode
+	pages       *PageReader
 	currentPage *Page
 	lastSegment int
+	bodyOffset  int
+
+	// buf is reused to assemble packets for Read.
+	buf []byte
+	// pending holds packets that have been read from the stream but not yet returned to the caller.
+	pending [][]byte
+
+	clock     clock
+	firstRead bool
+
+	// stripHeaders is set if header packets are kept from the caller. headers holds the header packets of the selected
+	// stream, and packets counts the packets read from it.
+	stripHeaders bool
+	headers      [][]byte
+	packets      int
+
+	// granule is the granule position of the last page whose final packet has been read.
+	granule int64
+	// lastPacketEnd is the index of the last segment of the current page that ends a packet, or -1 if none does.
+	lastPacketEnd int
+
+	// skipUntil is the granule position before which pages are dropped. Seeking is disabled while it is 0.
+	skipUntil int64
+	// dropContinuation is set when a dropped page ended in the middle of a packet.
+	dropContinuation bool
+
+	// sequences holds the sequence number expected for the next page of each stream.
+	sequences map[uint32]uint32
+
+	streams         []*Stream
+	streamsBySerial map[uint32]*Stream
+	selected        uint32
+	hasSelected     bool
+	chain           int
+	// chainOffset is the total duration of all previous chain links.
+	chainOffset     time.Duration
+	metadataChanged bool
 }
 
 func NewDecoder() *Decoder {
@@ -19,89 +58,127 @@ func NewDecoder() *Decoder {
 	}
 }
 
+// WithMode sets how corrupted pages are handled. Strict is used by default, which fails on the first bad page. In
+// Lenient mode, bad pages and garbage between pages are skipped, along with any packets broken by them.
+func (d *Decoder) WithMode(mode Mode) *Decoder {
+	d.mode = mode
+	return d
+}
+
+// WithoutHeaders makes the decoder keep the header packets of opus and vorbis streams to itself, so that only audio
+// packets are returned. The headers are still available through Headers.
+func (d *Decoder) WithoutHeaders() *Decoder {
+	d.stripHeaders = true
+	return d
+}
+
 func (d *Decoder) Open(r io.Reader) error {
 	d.r = r
+	d.pages = nil
+	if r != nil {
+		d.pages = NewPageReader(r, d.mode)
+	}
 	return nil
 }
 
-// Next returns the next packet of the stream. Returns io.EOF if all packets have already been read.
-func (d *Decoder) Next() ([]byte, error) {
-	var buf bytes.Buffer
+// OpenAt opens r like Open, but skips over all pages that end before offset. Header packets at the beginning of the
+// stream are still returned, unless they are stripped. If r is an io.ReadSeeker, the offset is found with SeekTime,
+// otherwise pages are read and dropped until it is reached. ErrUnknownCodec is returned if the stream's codec doesn't
+// have a known granule rate.
+func (d *Decoder) OpenAt(r io.Reader, offset time.Duration) error {
+	if err := d.Open(r); err != nil {
+		return err
+	}
 
-	readComplete := false
-	for !readComplete {
-		if d.currentPage == nil || d.lastSegment >= len(d.currentPage.SegmentTable) {
-			page, err := ReadPage(d.r)
-			if err != nil {
-				return nil, err
-			}
+	if offset <= 0 {
+		return nil
+	}
 
-			if len(page.SegmentTable) == 0 {
-				continue
-			}
+	if _, ok := r.(io.ReadSeeker); ok {
+		return d.SeekTime(offset)
+	}
 
-			d.currentPage = &page
-			d.lastSegment = 0
-		}
+	// Read the identification header so the granule rate is known before any audio pages are reached.
+	if err := d.readHeaders(1); err != nil {
+		return err
+	}
 
-		if _, err := io.CopyN(&buf, d.r, int64(d.currentPage.SegmentTable[d.lastSegment])); err != nil {
-			return nil, err
-		}
+	if d.clock.rate == 0 {
+		return ErrUnknownCodec
+	}
 
-		readComplete = d.currentPage.SegmentTable[d.lastSegment] < 255
-		d.lastSegment++
+	d.skipUntil = d.clock.Granule(offset)
+
+	return nil
+}
+
+// Next returns the next packet of the stream. Returns io.EOF if all packets have already been read.
+func (d *Decoder) Next() ([]byte, error) {
+	if len(d.pending) > 0 {
+		packet := d.pending[0]
+		d.pending = d.pending[1:]
+		return packet, nil
 	}
 
-	return buf.Bytes(), nil
+	return d.readPacket(nil)
 }
 
 // Decode decodes the next packet into p. The number of bytes written and any errors encountered are returned. If p is
-// smaller than the packet read, io.ErrShortBuffer will be returned.
+// smaller than the packet read, io.ErrShortBuffer will be returned and the packet is kept for the next call.
 func (d *Decoder) Read(p []byte) (int, error) {
-	pos := 0
-	bytesToRead := 0
-
-	for {
-		if d.currentPage == nil || d.lastSegment >= len(d.currentPage.SegmentTable) {
-			page, err := ReadPage(d.r)
-			if err != nil {
-				return 0, err
-			}
-
-			if len(page.SegmentTable) == 0 {
-				continue
-			}
-
-			d.currentPage = &page
-			d.lastSegment = 0
+	var packet []byte
+	if len(d.pending) > 0 {
+		packet = d.pending[0]
+	} else {
+		var err error
+		if d.buf, err = d.readPacket(d.buf[:0]); err != nil {
+			return 0, err
 		}
+		packet = d.buf
+	}
 
-		// Calculate how many bytes to be read
-		for ; d.lastSegment < len(d.currentPage.SegmentTable); d.lastSegment++ {
-			bytesToRead += int(d.currentPage.SegmentTable[d.lastSegment])
-			if d.currentPage.SegmentTable[d.lastSegment] < 255 {
-				d.lastSegment++
-				break
-			}
+	if len(p) < len(packet) {
+		if len(d.pending) == 0 {
+			d.pending = [][]byte{append([]byte(nil), packet...)}
 		}
+		return 0, io.ErrShortBuffer
+	}
 
-		if len(p) < bytesToRead {
-			return 0, io.ErrShortBuffer
-		}
+	if len(d.pending) > 0 {
+		d.pending = d.pending[1:]
+	}
 
-		// If we hit the end of the segment table with more data on the next page
-		if d.lastSegment == len(d.currentPage.SegmentTable) && d.currentPage.SegmentTable[d.lastSegment-1] == 255 {
-			n, err := d.r.Read(p[pos:bytesToRead])
-			if err != nil {
-				return bytesToRead, err
-			}
+	return copy(p, packet), nil
+}
 
-			pos += n
-			continue
-		}
+// Headers returns the header packets of the selected stream read so far. For opus streams, these are the OpusHead and
+// OpusTags packets, and for vorbis streams the identification, comment and setup headers. They start over with every
+// new link of a chained stream.
+func (d *Decoder) Headers() [][]byte {
+	return d.headers
+}
+
+// GranulePosition returns the granule position of the most recently completed page.
+func (d *Decoder) GranulePosition() int64 {
+	return d.granule
+}
 
-		return d.r.Read(p[pos:bytesToRead])
+// OutputGain returns the gain in dB that the header of the current opus stream asks to be applied when decoding it, or
+// 0 for other codecs and before the header has been read. Packets are returned as they are, so the gain is left to
+// whoever decodes them.
+func (d *Decoder) OutputGain() float64 {
+	return d.clock.gain
+}
+
+// Position returns the media time of the packets read so far, based on the granule position of the most recently
+// completed page. The position is only known for codecs with a recognized identification header, and not while pages
+// are still dropped to reach the offset passed to OpenAt.
+func (d *Decoder) Position() (time.Duration, bool) {
+	if d.clock.rate == 0 || d.skipUntil > 0 {
+		return 0, false
 	}
+
+	return d.chainOffset + d.clock.Duration(d.granule), true
 }
 
 func (d *Decoder) ReadAll() ([][]byte, error) {
@@ -124,5 +201,167 @@ func (d *Decoder) Close() error {
 	d.r = nil
 	d.currentPage = nil
 	d.lastSegment = 0
+	d.bodyOffset = 0
+	d.pending = nil
+	d.clock = clock{}
+	d.firstRead = false
+	d.headers = nil
+	d.packets = 0
+	d.granule = 0
+	d.skipUntil = 0
+	d.dropContinuation = false
+	d.pages = nil
+	d.sequences = nil
+	d.streams = nil
+	d.streamsBySerial = nil
+	d.hasSelected = false
+	d.chain = 0
+	d.chainOffset = 0
+	d.metadataChanged = false
+	return nil
+}
+
+// readPacket appends the next packet of the stream to dst and returns the extended slice.
+func (d *Decoder) readPacket(dst []byte) ([]byte, error) {
+	start := len(dst)
+
+	for {
+		if d.currentPage == nil || d.lastSegment >= len(d.currentPage.SegmentTable) {
+			gap, err := d.nextPage()
+			if err != nil {
+				return nil, err
+			}
+			if gap {
+				// The rest of the packet was lost along with the missing pages.
+				dst = dst[:start]
+			}
+			continue
+		}
+
+		lacing := int(d.currentPage.SegmentTable[d.lastSegment])
+		dst = append(dst, d.currentPage.Body[d.bodyOffset:d.bodyOffset+lacing]...)
+		d.bodyOffset += lacing
+		d.lastSegment++
+
+		if lacing < 255 {
+			if d.lastSegment-1 == d.lastPacketEnd && d.currentPage.Header.GranulePosition >= 0 {
+				d.granule = d.currentPage.Header.GranulePosition
+			}
+
+			if !d.firstRead {
+				d.firstRead = true
+				d.clock, _ = detectClock(dst[start:])
+			}
+
+			d.packets++
+			if d.packets <= d.clock.headers {
+				d.headers = append(d.headers, append([]byte(nil), dst[start:]...))
+				if d.stripHeaders {
+					dst = dst[:start]
+					continue
+				}
+			}
+
+			return dst, nil
+		}
+	}
+}
+
+// readHeaders reads packets until n header packets have been read, or the first packet if the codec isn't recognized.
+// Unless headers are stripped, the packets are queued to be returned to the caller.
+func (d *Decoder) readHeaders(n int) error {
+	strip := d.stripHeaders
+	d.stripHeaders = false
+	defer func() { d.stripHeaders = strip }()
+
+	for !d.firstRead || d.packets < min(n, d.clock.headers) {
+		packet, err := d.readPacket(nil)
+		if err != nil {
+			return err
+		}
+		if !strip {
+			d.pending = append(d.pending, packet)
+		}
+	}
+
 	return nil
 }
+
+// nextPage reads pages of the selected stream until one with at least one segment is found, dropping any pages that end
+// before skipUntil. gap is true if pages were missing in front of the returned page, in which case the first packet
+// continued onto it has already been dropped.
+func (d *Decoder) nextPage() (gap bool, err error) {
+	if d.pages == nil {
+		return false, ErrInvalid
+	}
+
+	for {
+		page, err := d.pages.ReadPage()
+		if err != nil {
+			return false, err
+		}
+
+		if !d.track(&page) {
+			continue
+		}
+
+		serial := page.Header.BitstreamSerialNumber
+		if d.sequences == nil {
+			d.sequences = make(map[uint32]uint32)
+		}
+		if sequence, ok := d.sequences[serial]; ok && page.Header.PageSequenceNumber != sequence {
+			gap = true
+			d.dropContinuation = true
+		}
+		d.sequences[serial] = page.Header.PageSequenceNumber + 1
+
+		if len(page.SegmentTable) == 0 {
+			continue
+		}
+
+		if d.skipUntil > 0 {
+			// Header pages have a granule position of 0 and are always kept. Pages that don't finish a packet have a
+			// granule position of -1, which can't be placed in time, so they are dropped along with their neighbours.
+			if granule := page.Header.GranulePosition; granule != 0 && granule < d.skipUntil {
+				d.dropContinuation = page.SegmentTable[len(page.SegmentTable)-1] == 255
+				if granule > 0 {
+					d.granule = granule
+				}
+				continue
+			}
+
+			if page.Header.GranulePosition > 0 {
+				d.skipUntil = 0
+			}
+		}
+
+		d.currentPage = &page
+		d.lastSegment = 0
+		d.bodyOffset = 0
+		d.lastPacketEnd = -1
+		for i, lacing := range page.SegmentTable {
+			if lacing < 255 {
+				d.lastPacketEnd = i
+			}
+		}
+
+		continued := false
+		if d.dropContinuation && page.Header.HeaderTypeFlag&ContinuedPacket != 0 {
+			// Discard the tail of a packet whose beginning was on a dropped page.
+			continued = true
+			for d.lastSegment < len(page.SegmentTable) {
+				lacing := int(page.SegmentTable[d.lastSegment])
+				d.bodyOffset += lacing
+				d.lastSegment++
+				if lacing < 255 {
+					continued = false
+					break
+				}
+			}
+		}
+		// A packet spanning more than two pages continues on the next page as well.
+		d.dropContinuation = continued
+
+		return gap, nil
+	}
+}
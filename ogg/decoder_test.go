@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"math/rand"
+	"slices"
 	"testing"
 	"time"
 
@@ -139,6 +140,412 @@ func TestDecoder_ReadAll(t *testing.T) {
 	}
 }
 
+func TestDecoder_OpenAt(t *testing.T) {
+	type test struct {
+		offset   time.Duration
+		seekable bool
+		first    byte
+		position time.Duration
+	}
+
+	tests := map[string]test{
+		"start":           {0, false, 0, 20 * time.Millisecond},
+		"middle":          {time.Second, false, 49, time.Second},
+		"end":             {10 * time.Second, false, 0, 0},
+		"seekable_start":  {0, true, 0, 20 * time.Millisecond},
+		"seekable_middle": {time.Second, true, 49, time.Second},
+		"seekable_end":    {10 * time.Second, true, 0, 0},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := ogg.NewDecoder()
+			var r io.Reader = genOpus(100)
+			if !tst.seekable {
+				r = struct{ io.Reader }{r}
+			}
+			if err := d.OpenAt(r, tst.offset); err != nil {
+				t.Fatal(err)
+			}
+
+			// The header packets are always returned.
+			for _, header := range []string{"OpusHead", "OpusTags"} {
+				packet, err := d.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.HasPrefix(packet, []byte(header)) {
+					t.Fatalf("expected %s packet; got %q", header, packet)
+				}
+			}
+
+			// Pages before the offset haven't been dropped yet, so the position isn't known.
+			if _, ok := d.Position(); ok && !tst.seekable && tst.offset > 0 {
+				t.Fatal("expected unknown position before the offset is reached")
+			}
+
+			packet, err := d.Next()
+			if tst.position == 0 {
+				if err != io.EOF {
+					t.Fatalf("expected %q error; got %v", io.EOF, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if packet[0] != tst.first {
+				t.Fatalf("expected packet %d; got %d", tst.first, packet[0])
+			}
+			if position, ok := d.Position(); !ok || position != tst.position {
+				t.Fatalf("expected position %s; got %s", tst.position, position)
+			}
+		})
+	}
+}
+
+func TestDecoder_SeekTime(t *testing.T) {
+	type test struct {
+		// read is the number of audio packets read before seeking.
+		read         int
+		offset       time.Duration
+		stripHeaders bool
+		first        int
+		err          error
+	}
+
+	// Long enough for the bisection to have to narrow down the range several times.
+	const nPackets = 3000
+
+	tests := map[string]test{
+		"start":         {0, 0, false, 0, nil},
+		"middle":        {0, 30 * time.Second, false, 1499, nil},
+		"near_end":      {0, 59990 * time.Millisecond, false, 2999, nil},
+		"past_end":      {0, time.Hour, false, nPackets, nil},
+		"backwards":     {2000, 10 * time.Second, false, 499, nil},
+		"forwards":      {10, 50 * time.Second, false, 2499, nil},
+		"strip_headers": {0, 30 * time.Second, true, 1499, nil},
+		"unseekable":    {0, 0, false, 0, ogg.ErrNotSeekable},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			var r io.Reader = bytes.NewReader(encodeOpus(1, nPackets))
+			if tst.err == ogg.ErrNotSeekable {
+				r = struct{ io.Reader }{r}
+			}
+
+			d := ogg.NewDecoder()
+			if tst.stripHeaders {
+				d.WithoutHeaders()
+			}
+			_ = d.Open(r)
+
+			for i := 0; i < tst.read; i++ {
+				if _, err := d.Next(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := d.SeekTime(tst.offset); !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			} else if err != nil {
+				return
+			}
+
+			if !tst.stripHeaders && tst.read == 0 {
+				for _, header := range []string{"OpusHead", "OpusTags"} {
+					if packet, err := d.Next(); err != nil || !bytes.HasPrefix(packet, []byte(header)) {
+						t.Fatalf("expected %s packet; got %q, %v", header, packet, err)
+					}
+				}
+			}
+
+			packet, err := d.Next()
+			if tst.first == nPackets {
+				if err != io.EOF {
+					t.Fatalf("expected %q error; got %v", io.EOF, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if packet[0] != byte(tst.first) {
+				t.Fatalf("expected packet %d; got %d", byte(tst.first), packet[0])
+			}
+			expected := time.Duration(tst.first+1) * 20 * time.Millisecond
+			if position, _ := d.Position(); position != expected {
+				t.Fatalf("expected position %s; got %s", expected, position)
+			}
+		})
+	}
+}
+
+func TestDecoder_WithoutHeaders(t *testing.T) {
+	type test struct {
+		data    []byte
+		offset  time.Duration
+		packets int
+		first   byte
+	}
+
+	tests := map[string]test{
+		"start":   {encodeOpus(1, 10), 0, 10, 0},
+		"offset":  {encodeOpus(1, 100), time.Second, 51, 49},
+		"chained": {append(encodeOpus(1, 3), encodeOpus(2, 3)...), 0, 6, 0},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := ogg.NewDecoder().WithoutHeaders()
+			if err := d.OpenAt(bytes.NewReader(tst.data), tst.offset); err != nil {
+				t.Fatal(err)
+			}
+
+			packets, err := d.ReadAll()
+			if err != io.EOF {
+				t.Fatalf("expected %q error; got %v", io.EOF, err)
+			}
+			if len(packets) != tst.packets {
+				t.Fatalf("expected %d packets; got %d", tst.packets, len(packets))
+			}
+			if packets[0][0] != tst.first {
+				t.Fatalf("expected packet %d first; got %d", tst.first, packets[0][0])
+			}
+
+			headers := d.Headers()
+			if len(headers) != 2 || !bytes.HasPrefix(headers[0], []byte("OpusHead")) ||
+				!bytes.HasPrefix(headers[1], []byte("OpusTags")) {
+				t.Fatalf("expected OpusHead and OpusTags headers; got %q", headers)
+			}
+		})
+	}
+}
+
+func TestDecoder_OutputGain(t *testing.T) {
+	type test struct {
+		gain int16
+		want float64
+	}
+
+	tests := map[string]test{
+		"none":     {0, 0},
+		"positive": {3 * 256, 3},
+		"negative": {-384, -1.5},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := ogg.NewEncoder(&buf, 1)
+			head := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+			ogg.ByteOrder.PutUint16(head[16:18], uint16(tst.gain))
+			_ = e.WritePacket(head, 0)
+			_ = e.WritePacket([]byte("OpusTags"), 0)
+			_ = e.WritePacket([]byte{0xf8, 0xff, 0xfe}, 1272)
+			_ = e.Close()
+
+			d := ogg.NewDecoder().WithoutHeaders()
+			if err := d.Open(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := d.ReadAll(); err != io.EOF {
+				t.Fatalf("expected %q error; got %v", io.EOF, err)
+			}
+
+			if got := d.OutputGain(); got != tst.want {
+				t.Fatalf("expected output gain %v dB; got %v dB", tst.want, got)
+			}
+		})
+	}
+}
+
+func TestDecoder_WithMode(t *testing.T) {
+	type test struct {
+		mode    ogg.Mode
+		modify  func(pages [][]byte) [][]byte
+		packets int
+		err     error
+	}
+
+	corrupt := func(pages [][]byte) [][]byte {
+		pages[10][len(pages[10])-1] ^= 0xff
+		return pages
+	}
+	prefix := func(pages [][]byte) [][]byte {
+		return append([][]byte{[]byte("garbage")}, pages...)
+	}
+	between := func(pages [][]byte) [][]byte {
+		garbage := append([]byte("OggS"), make([]byte, 40)...)
+		return append(pages[:10], append([][]byte{garbage}, pages[10:]...)...)
+	}
+	garbage := func([][]byte) [][]byte {
+		return [][]byte{make([]byte, 100)}
+	}
+	truncate := func(pages [][]byte) [][]byte {
+		return append(pages[:20], pages[20][:30])
+	}
+
+	tests := map[string]test{
+		"strict_valid":     {ogg.Strict, nil, 102, io.EOF},
+		"strict_corrupt":   {ogg.Strict, corrupt, 10, ogg.ErrBadChecksum},
+		"strict_prefix":    {ogg.Strict, prefix, 0, ogg.ErrInvalid},
+		"strict_between":   {ogg.Strict, between, 10, ogg.ErrBadChecksum},
+		"lenient_valid":    {ogg.Lenient, nil, 102, io.EOF},
+		"lenient_corrupt":  {ogg.Lenient, corrupt, 101, io.EOF},
+		"lenient_prefix":   {ogg.Lenient, prefix, 102, io.EOF},
+		"lenient_between":  {ogg.Lenient, between, 102, io.EOF},
+		"lenient_garbage":  {ogg.Lenient, garbage, 0, ogg.ErrInvalid},
+		"lenient_truncate": {ogg.Lenient, truncate, 20, io.EOF},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			pages := splitPages(t, genOpus(100))
+			if tst.modify != nil {
+				pages = tst.modify(pages)
+			}
+
+			d := ogg.NewDecoder().WithMode(tst.mode)
+			_ = d.Open(bytes.NewReader(bytes.Join(pages, nil)))
+
+			packets := 0
+			var err error
+			for ; err == nil; packets++ {
+				_, err = d.Next()
+			}
+			packets--
+
+			if err != tst.err {
+				t.Fatalf("expected %q error; got %q", tst.err, err)
+			}
+			if packets != tst.packets {
+				t.Fatalf("expected %d packets; got %d", tst.packets, packets)
+			}
+		})
+	}
+}
+
+// splitPages returns the encoded pages read from r.
+func splitPages(t *testing.T, r io.Reader) [][]byte {
+	t.Helper()
+
+	var pages [][]byte
+	for {
+		page, err := ogg.ReadPage(r)
+		if err == io.EOF {
+			return pages
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page.Serialize())
+	}
+}
+
+func TestDecoder_SelectStream(t *testing.T) {
+	type test struct {
+		data    []byte
+		select_ uint32
+		serials []uint32
+		chains  int
+		// packets holds the serial number of every audio packet expected.
+		packets  []uint32
+		position time.Duration
+	}
+
+	a := splitPages(t, bytes.NewReader(encodeOpus(1, 3)))
+	b := splitPages(t, bytes.NewReader(encodeOpus(2, 3)))
+
+	var multiplexed [][]byte
+	for i := range a {
+		multiplexed = append(multiplexed, a[i], b[i])
+	}
+
+	chained := bytes.Join(append(a, b...), nil)
+
+	tests := map[string]test{
+		"single":      {bytes.Join(a, nil), 0, []uint32{1}, 1, []uint32{1, 1, 1}, 60 * time.Millisecond},
+		"chained":     {chained, 0, []uint32{1, 2}, 2, []uint32{1, 1, 1, 2, 2, 2}, 120 * time.Millisecond},
+		"multiplexed": {bytes.Join(multiplexed, nil), 0, []uint32{1, 2}, 1, []uint32{1, 1, 1}, 60 * time.Millisecond},
+		"selected":    {bytes.Join(multiplexed, nil), 2, []uint32{1, 2}, 1, []uint32{2, 2, 2}, 60 * time.Millisecond},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := ogg.NewDecoder()
+			_ = d.Open(bytes.NewReader(tst.data))
+			if tst.select_ != 0 {
+				d.SelectStream(tst.select_)
+			}
+
+			var packets []uint32
+			changes := 0
+			for {
+				packet, err := d.Next()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				if d.MetadataChanged() {
+					changes++
+				}
+				if !bytes.HasPrefix(packet, []byte("Opus")) {
+					packets = append(packets, uint32(packet[1]))
+				}
+			}
+
+			if !slices.Equal(packets, tst.packets) {
+				t.Fatalf("expected packets from streams %v; got %v", tst.packets, packets)
+			}
+			if changes != tst.chains-1 {
+				t.Fatalf("expected %d metadata changes; got %d", tst.chains-1, changes)
+			}
+			if position, _ := d.Position(); position != tst.position {
+				t.Fatalf("expected position %s; got %s", tst.position, position)
+			}
+
+			streams := d.Streams()
+			if len(streams) != len(tst.serials) {
+				t.Fatalf("expected %d streams; got %d", len(tst.serials), len(streams))
+			}
+			for i, stream := range streams {
+				if stream.Serial != tst.serials[i] || stream.Codec != "opus" || !stream.Ended {
+					t.Fatalf("unexpected stream %+v", stream)
+				}
+			}
+		})
+	}
+}
+
+// genOpus creates a reader to a dummy opus stream with nPackets audio packets of 20ms. See encodeOpus.
+func genOpus(nPackets int) io.Reader {
+	return bytes.NewReader(encodeOpus(1, nPackets))
+}
+
+// encodeOpus encodes a dummy opus stream with nPackets audio packets of 20ms, each on their own page. Every packet
+// starts with its index, followed by the stream's serial number.
+func encodeOpus(serial uint32, nPackets int) []byte {
+	var buf bytes.Buffer
+	e := ogg.NewEncoder(&buf, serial)
+	e.SetPageSize(1)
+
+	head := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+	_ = e.WritePacket(head, 0)
+	_ = e.WritePacket([]byte("OpusTags"), 0)
+
+	for i := 0; i < nPackets; i++ {
+		packet := bytes.Repeat([]byte{byte(i)}, 100)
+		packet[1] = byte(serial)
+		_ = e.WritePacket(packet, int64(312+(i+1)*960))
+	}
+	_ = e.Close()
+
+	return buf.Bytes()
+}
+
 // genOgg creates a reader to a dummy ogg file with nPages containing packets of size packetSize bytes
 func genOgg(nPages int, packetSize int) io.Reader {
 	var buf bytes.Buffer
@@ -184,11 +591,8 @@ func genOgg(nPages int, packetSize int) io.Reader {
 			page.SegmentTable[page.Header.NumberPageSegments-1] = 0
 		}
 
+		page.Body = make([]byte, page.BodySize())
 		buf.Write(page.Serialize())
-
-		for _, table := range segmentTable {
-			buf.Write(make([]byte, table))
-		}
 	}
 
 	return bytes.NewReader(buf.Bytes())
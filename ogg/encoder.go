@@ -0,0 +1,153 @@
+package ogg
+
+import (
+	"io"
+)
+
+// DefaultPageSize is the body size at which the Encoder starts a new page by default.
+const DefaultPageSize = 4096
+
+// Encoder writes packets as a single logical bitstream of ogg pages. Packets are collected into a page until its body
+// reaches the page size or it runs out of segments, at which point the page is written. Packets that don't fit are
+// continued on the next page.
+type Encoder struct {
+	w        io.Writer
+	serial   uint32
+	pageSize int
+
+	sequence uint32
+	started  bool
+	closed   bool
+
+	// segments and body hold the page being assembled.
+	segments []byte
+	body     []byte
+	// granule is the granule position of the last packet that ends on the page being assembled, or -1 if none does.
+	granule int64
+	// lastGranule is the granule position of the last packet written.
+	lastGranule int64
+	// continued is set if the page being assembled starts with the rest of a packet from the previous page.
+	continued bool
+}
+
+// NewEncoder creates an Encoder that writes pages with the given bitstream serial number to w.
+func NewEncoder(w io.Writer, serial uint32) *Encoder {
+	return &Encoder{
+		w:        w,
+		serial:   serial,
+		pageSize: DefaultPageSize,
+		granule:  -1,
+	}
+}
+
+// SetPageSize sets the body size at which a page is written, which trades overhead for latency. Pages are still written
+// earlier if they run out of segments, and a single page can never hold more than MaxSegments * 255 bytes.
+func (e *Encoder) SetPageSize(size int) {
+	if size > 0 {
+		e.pageSize = size
+	}
+}
+
+// WritePacket adds packet to the stream. granule is the granule position at the end of the packet, whose meaning
+// depends on the codec; header packets usually use 0. Any full pages are written to the underlying writer.
+func (e *Encoder) WritePacket(packet []byte, granule int64) error {
+	if e.closed {
+		return io.ErrClosedPipe
+	}
+
+	for {
+		// Lacing values of 255 mean the packet continues in the next segment, so a packet whose length is a multiple of
+		// 255 ends with an empty segment.
+		for len(packet) >= 255 && len(e.segments) < MaxSegments {
+			e.segments = append(e.segments, 255)
+			e.body = append(e.body, packet[:255]...)
+			packet = packet[255:]
+		}
+
+		if len(e.segments) < MaxSegments {
+			e.segments = append(e.segments, byte(len(packet)))
+			e.body = append(e.body, packet...)
+			e.granule = granule
+			e.lastGranule = granule
+			break
+		}
+
+		// The page ran out of segments in the middle of the packet.
+		if err := e.writePage(0); err != nil {
+			return err
+		}
+		e.continued = true
+	}
+
+	if len(e.body) >= e.pageSize || len(e.segments) == MaxSegments {
+		return e.writePage(0)
+	}
+
+	return nil
+}
+
+// Flush writes the page being assembled, even if it isn't full. Codecs often require header packets to be on pages of
+// their own, so Flush should be called after writing them. Streams should also be flushed before a pause in input, so
+// that listeners don't wait on a partial page.
+func (e *Encoder) Flush() error {
+	if e.closed {
+		return io.ErrClosedPipe
+	}
+
+	if len(e.segments) == 0 {
+		return nil
+	}
+
+	return e.writePage(0)
+}
+
+// Close writes the remaining packets on a final page marked as the end of the stream. If no packets are left, an empty
+// end of stream page is written. Close doesn't close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	if len(e.segments) == 0 {
+		e.granule = e.lastGranule
+	}
+
+	err := e.writePage(EndOfStream)
+	e.closed = true
+
+	return err
+}
+
+// writePage writes the page being assembled with the given additional flags and starts a new one.
+func (e *Encoder) writePage(flags byte) error {
+	if !e.started {
+		flags |= BeginningOfStream
+		e.started = true
+	}
+	if e.continued {
+		flags |= ContinuedPacket
+	}
+
+	page := Page{
+		Header: PageHeader{
+			CapturePattern:        CapturePattern,
+			HeaderTypeFlag:        flags,
+			GranulePosition:       e.granule,
+			BitstreamSerialNumber: e.serial,
+			PageSequenceNumber:    e.sequence,
+			NumberPageSegments:    uint8(len(e.segments)),
+		},
+		SegmentTable: e.segments,
+		Body:         e.body,
+	}
+
+	e.sequence++
+	e.segments = e.segments[:0]
+	e.body = e.body[:0]
+	e.granule = -1
+	e.continued = false
+
+	_, err := e.w.Write(page.Serialize())
+
+	return err
+}
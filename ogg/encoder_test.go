@@ -0,0 +1,145 @@
+package ogg_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo/ogg"
+)
+
+func TestEncoder_WritePacket(t *testing.T) {
+	type test struct {
+		sizes    []int
+		pageSize int
+	}
+
+	tests := map[string]test{
+		"empty":            {nil, ogg.DefaultPageSize},
+		"small_packets":    {[]int{1, 10, 100, 254}, ogg.DefaultPageSize},
+		"lacing_boundary":  {[]int{255, 510, 0, 256}, ogg.DefaultPageSize},
+		"continued_packet": {[]int{100, 255*ogg.MaxSegments + 1000, 100}, ogg.DefaultPageSize},
+		"page_per_packet":  {[]int{300, 300, 300}, 1},
+		"many_packets":     {randomSizes(1000, 400), 1024},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := ogg.NewEncoder(&buf, 1234)
+			e.SetPageSize(tst.pageSize)
+
+			packets := make([][]byte, len(tst.sizes))
+			for i, size := range tst.sizes {
+				packets[i] = bytes.Repeat([]byte{byte(i)}, size)
+				if err := e.WritePacket(packets[i], int64(i+1)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			checkPages(t, bytes.NewReader(buf.Bytes()))
+
+			d := ogg.NewDecoder()
+			_ = d.Open(bytes.NewReader(buf.Bytes()))
+
+			for i, packet := range packets {
+				got, err := d.Next()
+				if err != nil {
+					t.Fatalf("packet %d: %v", i, err)
+				}
+				if !bytes.Equal(got, packet) {
+					t.Fatalf("packet %d: expected %d bytes; got %d bytes", i, len(packet), len(got))
+				}
+			}
+
+			if _, err := d.Next(); err != io.EOF {
+				t.Fatalf("expected %q error; got %v", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestEncoder_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	e := ogg.NewEncoder(&buf, 0)
+
+	_ = e.WritePacket([]byte("header"), 0)
+	_ = e.Flush()
+	_ = e.WritePacket([]byte("audio"), 960)
+	_ = e.Close()
+
+	r := bytes.NewReader(buf.Bytes())
+	for i, expected := range []int64{0, 960} {
+		page, err := ogg.ReadPage(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.SegmentTable) != 1 {
+			t.Fatalf("page %d: expected 1 segment; got %d", i, len(page.SegmentTable))
+		}
+		if page.Header.GranulePosition != expected {
+			t.Fatalf("page %d: expected granule position %d; got %d", i, expected, page.Header.GranulePosition)
+		}
+	}
+}
+
+func TestEncoder_Closed(t *testing.T) {
+	e := ogg.NewEncoder(io.Discard, 0)
+	_ = e.Close()
+
+	if err := e.WritePacket([]byte{1}, 0); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("expected %q error; got %v", io.ErrClosedPipe, err)
+	}
+}
+
+// checkPages verifies the checksums, sequence numbers and stream flags of all pages read from r.
+func checkPages(t *testing.T, r io.Reader) {
+	t.Helper()
+
+	var pages []ogg.Page
+	for {
+		page, err := ogg.ReadPage(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+	}
+
+	if len(pages) == 0 {
+		t.Fatal("expected at least one page")
+	}
+
+	for i, page := range pages {
+		if page.Header.CRCChecksum != page.Checksum() {
+			t.Fatalf("page %d: expected checksum %08x; got %08x", i, page.Checksum(), page.Header.CRCChecksum)
+		}
+		if page.Header.PageSequenceNumber != uint32(i) {
+			t.Fatalf("page %d: unexpected sequence number %d", i, page.Header.PageSequenceNumber)
+		}
+		if bos := page.Header.HeaderTypeFlag&ogg.BeginningOfStream != 0; bos != (i == 0) {
+			t.Fatalf("page %d: unexpected beginning of stream flag", i)
+		}
+		if eos := page.Header.HeaderTypeFlag&ogg.EndOfStream != 0; eos != (i == len(pages)-1) {
+			t.Fatalf("page %d: unexpected end of stream flag", i)
+		}
+	}
+}
+
+func randomSizes(n int, max int) []int {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = rnd.Intn(max)
+	}
+
+	return sizes
+}
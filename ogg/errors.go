@@ -5,3 +5,6 @@ import (
 )
 
 var ErrInvalid = errors.New("invalid type")
+var ErrUnknownCodec = errors.New("unknown codec")
+var ErrBadChecksum = errors.New("page checksum mismatch")
+var ErrNotSeekable = errors.New("reader is not seekable")
@@ -0,0 +1,201 @@
+// Package opus parses the header packets that start every Ogg Opus stream.
+//
+// Implementation spec: https://www.rfc-editor.org/rfc/rfc7845
+package opus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/olympus-go/apollo/ogg"
+	"github.com/olympus-go/apollo/ogg/comment"
+)
+
+// SampleRate is the rate of granule positions and pre-skip in Ogg Opus streams, regardless of the input sample rate.
+const SampleRate = 48000
+
+var ErrInvalidHead = errors.New("invalid OpusHead packet")
+var ErrInvalidTags = errors.New("invalid OpusTags packet")
+var ErrInvalidPacket = errors.New("invalid opus packet")
+
+// SilenceFrame is a 20ms opus packet of silence, as commonly sent by voice transports when audio stops.
+var SilenceFrame = []byte{0xf8, 0xff, 0xfe}
+
+// maxPacketDuration is the longest duration a single opus packet can hold.
+const maxPacketDuration = 120 * time.Millisecond
+
+var headMagic = []byte("OpusHead")
+var tagsMagic = []byte("OpusTags")
+
+// Head is the identification header of an Ogg Opus stream.
+type Head struct {
+	Version  uint8
+	Channels uint8
+	// PreSkip is the number of 48kHz samples to discard from the start of the decoded output.
+	PreSkip uint16
+	// InputSampleRate is the sample rate of the original input. It is informational only, opus always decodes at
+	// SampleRate.
+	InputSampleRate uint32
+	// OutputGain is the gain to apply to the decoded output in dB, as a Q7.8 fixed point number. See Gain.
+	OutputGain int16
+	// MappingFamily describes how the channels are coded. Family 0 is mono or stereo in a single stream.
+	MappingFamily uint8
+
+	// StreamCount, CoupledCount and ChannelMapping are only set for mapping families other than 0.
+	StreamCount    uint8
+	CoupledCount   uint8
+	ChannelMapping []byte
+}
+
+// Gain returns OutputGain in dB.
+func (h Head) Gain() float64 {
+	return float64(h.OutputGain) / 256
+}
+
+// PreSkipDuration returns the time covered by PreSkip.
+func (h Head) PreSkipDuration() time.Duration {
+	return time.Duration(h.PreSkip) * time.Second / SampleRate
+}
+
+// IsHead reports whether packet looks like an OpusHead packet.
+func IsHead(packet []byte) bool {
+	return bytes.HasPrefix(packet, headMagic)
+}
+
+// ParseHead parses an OpusHead packet.
+func ParseHead(packet []byte) (Head, error) {
+	if len(packet) < 19 || !IsHead(packet) {
+		return Head{}, ErrInvalidHead
+	}
+
+	h := Head{
+		Version:         packet[8],
+		Channels:        packet[9],
+		PreSkip:         binary.LittleEndian.Uint16(packet[10:12]),
+		InputSampleRate: binary.LittleEndian.Uint32(packet[12:16]),
+		OutputGain:      int16(binary.LittleEndian.Uint16(packet[16:18])),
+		MappingFamily:   packet[18],
+	}
+
+	// Only the major version in the upper 4 bits is incompatible.
+	if h.Version>>4 != 0 || h.Channels == 0 {
+		return Head{}, ErrInvalidHead
+	}
+
+	if h.MappingFamily == 0 {
+		if h.Channels > 2 {
+			return Head{}, ErrInvalidHead
+		}
+		return h, nil
+	}
+
+	if len(packet) < 21+int(h.Channels) {
+		return Head{}, ErrInvalidHead
+	}
+
+	h.StreamCount = packet[19]
+	h.CoupledCount = packet[20]
+	h.ChannelMapping = append([]byte(nil), packet[21:21+int(h.Channels)]...)
+
+	if h.StreamCount == 0 || h.CoupledCount > h.StreamCount {
+		return Head{}, ErrInvalidHead
+	}
+
+	return h, nil
+}
+
+// Tags is the comment header of an Ogg Opus stream.
+type Tags = comment.Header
+
+// IsTags reports whether packet looks like an OpusTags packet.
+func IsTags(packet []byte) bool {
+	return bytes.HasPrefix(packet, tagsMagic)
+}
+
+// ParseTags parses an OpusTags packet.
+func ParseTags(packet []byte) (Tags, error) {
+	if !IsTags(packet) {
+		return Tags{}, ErrInvalidTags
+	}
+	// Anything after the comments is reserved, and ignored.
+	t, _, err := comment.Parse(packet[len(tagsMagic):])
+	if err != nil {
+		return Tags{}, ErrInvalidTags
+	}
+
+	return t, nil
+}
+
+// ReadHeaders reads the OpusHead and OpusTags headers from the beginning of the ogg stream in r.
+func ReadHeaders(r io.Reader) (Head, Tags, error) {
+	d := ogg.NewDecoder()
+	_ = d.Open(r)
+
+	packet, err := d.Next()
+	if err != nil {
+		return Head{}, Tags{}, err
+	}
+	head, err := ParseHead(packet)
+	if err != nil {
+		return Head{}, Tags{}, err
+	}
+
+	if packet, err = d.Next(); err != nil {
+		return Head{}, Tags{}, err
+	}
+	tags, err := ParseTags(packet)
+	if err != nil {
+		return Head{}, Tags{}, err
+	}
+
+	return head, tags, nil
+}
+
+// PacketDuration returns the duration of the audio in an opus packet, as described by its TOC byte.
+// ErrInvalidPacket is returned if the packet is empty or describes more than 120ms of audio.
+//
+// See https://www.rfc-editor.org/rfc/rfc6716#section-3.1
+func PacketDuration(packet []byte) (time.Duration, error) {
+	if len(packet) == 0 {
+		return 0, ErrInvalidPacket
+	}
+
+	toc := packet[0]
+	config := toc >> 3
+
+	var frame time.Duration
+	switch {
+	case config < 12:
+		// SILK only: 10, 20, 40 or 60ms.
+		frame = [...]time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16:
+		// Hybrid: 10 or 20ms.
+		frame = [...]time.Duration{10, 20}[config%2] * time.Millisecond
+	default:
+		// CELT only: 2.5, 5, 10 or 20ms.
+		frame = [...]time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+
+	var frames int
+	switch toc & 0x03 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, ErrInvalidPacket
+		}
+		frames = int(packet[1] & 0x3f)
+	}
+
+	duration := time.Duration(frames) * frame
+	if frames == 0 || duration > maxPacketDuration {
+		return 0, ErrInvalidPacket
+	}
+
+	return duration, nil
+}
@@ -0,0 +1,164 @@
+package opus_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo/ogg/opus"
+)
+
+func TestParseHead(t *testing.T) {
+	type test struct {
+		packet []byte
+		head   opus.Head
+		err    error
+	}
+
+	stereo := []byte("OpusHead\x01\x02\x38\x01\x44\xac\x00\x00\x00\xfd\x00")
+	surround := []byte("OpusHead\x01\x06\x38\x01\x80\xbb\x00\x00\x00\x00\x01\x04\x02\x00\x04\x01\x02\x03\x05")
+	wrongMagic := []byte("OpusTags\x01\x02\x38\x01\x44\xac\x00\x00\x00\xfd\x00")
+	majorVersion := []byte("OpusHead\x10\x02\x38\x01\x44\xac\x00\x00\x00\xfd\x00")
+	family0Surround := []byte("OpusHead\x01\x03\x38\x01\x44\xac\x00\x00\x00\xfd\x00")
+
+	tests := map[string]test{
+		"stereo": {stereo, opus.Head{
+			Version: 1, Channels: 2, PreSkip: 312, InputSampleRate: 44100, OutputGain: -768,
+		}, nil},
+		"surround": {surround, opus.Head{
+			Version: 1, Channels: 6, PreSkip: 312, InputSampleRate: 48000, MappingFamily: 1,
+			StreamCount: 4, CoupledCount: 2, ChannelMapping: []byte{0, 4, 1, 2, 3, 5},
+		}, nil},
+		"short":             {stereo[:18], opus.Head{}, opus.ErrInvalidHead},
+		"wrong_magic":       {wrongMagic, opus.Head{}, opus.ErrInvalidHead},
+		"major_version":     {majorVersion, opus.Head{}, opus.ErrInvalidHead},
+		"missing_mapping":   {surround[:24], opus.Head{}, opus.ErrInvalidHead},
+		"family_0_surround": {family0Surround, opus.Head{}, opus.ErrInvalidHead},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			head, err := opus.ParseHead(tst.packet)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(head, tst.head) {
+				t.Fatalf("expected %+v; got %+v", tst.head, head)
+			}
+		})
+	}
+}
+
+func TestHead_Gain(t *testing.T) {
+	head := opus.Head{OutputGain: -768, PreSkip: 312}
+
+	if gain := head.Gain(); gain != -3 {
+		t.Fatalf("expected gain -3dB; got %vdB", gain)
+	}
+	if preSkip := head.PreSkipDuration(); preSkip != 6500*time.Microsecond {
+		t.Fatalf("expected pre-skip 6.5ms; got %s", preSkip)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	type test struct {
+		packet []byte
+		tags   opus.Tags
+		err    error
+	}
+
+	valid := genTags("libopus 1.4", "TITLE=Song", "artist=Someone", "R128_TRACK_GAIN=-512")
+	hugeCount := genTags("vendor")
+	binary.LittleEndian.PutUint32(hugeCount[len(hugeCount)-4:], 0xffffffff)
+
+	tests := map[string]test{
+		"valid": {valid, opus.Tags{
+			Vendor: "libopus 1.4", Comments: []string{"TITLE=Song", "artist=Someone", "R128_TRACK_GAIN=-512"},
+		}, nil},
+		"no_comments": {genTags("vendor"), opus.Tags{Vendor: "vendor", Comments: []string{}}, nil},
+		// Anything after the comments, e.g. padding, is allowed.
+		"trailing_data": {append(genTags("vendor"), 0, 0, 0), opus.Tags{Vendor: "vendor", Comments: []string{}}, nil},
+		"wrong_magic":   {append([]byte("OpusHead"), valid[8:]...), opus.Tags{}, opus.ErrInvalidTags},
+		"truncated":     {valid[:len(valid)-1], opus.Tags{}, opus.ErrInvalidTags},
+		"huge_count":    {hugeCount, opus.Tags{}, opus.ErrInvalidTags},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			tags, err := opus.ParseTags(tst.packet)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			}
+			if tags.Vendor != tst.tags.Vendor || !slices.Equal(tags.Comments, tst.tags.Comments) {
+				t.Fatalf("expected %+v; got %+v", tst.tags, tags)
+			}
+		})
+	}
+}
+
+func TestTags_Get(t *testing.T) {
+	tags := opus.Tags{Comments: []string{"TITLE=Song", "ARTIST=First", "artist=Second", "EMPTY="}}
+
+	if v, ok := tags.Get("Artist"); !ok || v != "First" {
+		t.Fatalf("expected First; got %q", v)
+	}
+	if v, ok := tags.Get("empty"); !ok || v != "" {
+		t.Fatalf("expected empty value; got %q, %v", v, ok)
+	}
+	if _, ok := tags.Get("album"); ok {
+		t.Fatal("expected album to be missing")
+	}
+}
+
+func TestPacketDuration(t *testing.T) {
+	type test struct {
+		packet   []byte
+		duration time.Duration
+		err      error
+	}
+
+	tests := map[string]test{
+		"silence":         {opus.SilenceFrame, 20 * time.Millisecond, nil},
+		"silk_60ms":       {[]byte{3 << 3}, 60 * time.Millisecond, nil},
+		"hybrid_10ms":     {[]byte{12 << 3}, 10 * time.Millisecond, nil},
+		"celt_2_5ms":      {[]byte{16 << 3}, 2500 * time.Microsecond, nil},
+		"two_frames":      {[]byte{31<<3 | 1}, 40 * time.Millisecond, nil},
+		"arbitrary_count": {[]byte{31<<3 | 3, 6}, 120 * time.Millisecond, nil},
+		"too_long":        {[]byte{31<<3 | 3, 7}, 0, opus.ErrInvalidPacket},
+		"no_frames":       {[]byte{31<<3 | 3, 0}, 0, opus.ErrInvalidPacket},
+		"missing_count":   {[]byte{31<<3 | 3}, 0, opus.ErrInvalidPacket},
+		"empty":           {nil, 0, opus.ErrInvalidPacket},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			duration, err := opus.PacketDuration(tst.packet)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			}
+			if duration != tst.duration {
+				t.Fatalf("expected duration %s; got %s", tst.duration, duration)
+			}
+		})
+	}
+}
+
+func genTags(vendor string, comments ...string) []byte {
+	packet := []byte("OpusTags")
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(vendor)))
+	packet = append(packet, vendor...)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(comments)))
+	for _, comment := range comments {
+		packet = binary.LittleEndian.AppendUint32(packet, uint32(len(comment)))
+		packet = append(packet, comment...)
+	}
+
+	return packet
+}
@@ -8,13 +8,24 @@ import (
 
 // Implementation spec: https://www.xiph.org/ogg/doc/rfc3533.txt
 
-// TODO actually do CRC checks hehe
-const generatorPolynomial = `0x04c11db7`
 const MaxPageSize = 65307
 
+// headerSize is the size of an encoded PageHeader.
+const headerSize = 27
+
+// MaxSegments is the maximum number of segments a single page can hold.
+const MaxSegments = 255
+
 // ByteOrder is the byte order used by ogg containers.
 var ByteOrder = binary.LittleEndian
 
+// Bit flags of PageHeader.HeaderTypeFlag.
+const (
+	ContinuedPacket   byte = 0x01
+	BeginningOfStream byte = 0x02
+	EndOfStream       byte = 0x04
+)
+
 // CapturePattern is the 4 byte field used to denote the beginning of a ogg page header.
 var CapturePattern = [4]byte{'O', 'g', 'g', 'S'}
 
@@ -59,6 +70,9 @@ type Page struct {
 
 	// Byte slice of size Header.NumberPageSegments containing the lacing values of all segments in this page.
 	SegmentTable []byte
+
+	// Byte slice containing the segment data of this page. Its length is the sum of all values in SegmentTable.
+	Body []byte
 }
 
 func ReadHeader(r io.Reader) (PageHeader, error) {
@@ -80,6 +94,9 @@ func ReadHeader(r io.Reader) (PageHeader, error) {
 	return header, err
 }
 
+// ReadPage reads the next page from r and verifies its checksum. ErrInvalid is returned if r isn't positioned at the
+// start of a page, and ErrBadChecksum if the page is corrupted, in which case the page is still returned. A PageReader
+// can be used to skip over corrupted data instead.
 func ReadPage(r io.Reader) (page Page, err error) {
 	page.Header, err = ReadHeader(r)
 	if err != nil {
@@ -87,11 +104,32 @@ func ReadPage(r io.Reader) (page Page, err error) {
 	}
 
 	page.SegmentTable = make([]byte, page.Header.NumberPageSegments)
-	_, err = io.ReadFull(r, page.SegmentTable)
+	if _, err = io.ReadFull(r, page.SegmentTable); err != nil {
+		return
+	}
+
+	page.Body = make([]byte, page.BodySize())
+	if _, err = io.ReadFull(r, page.Body); err != nil {
+		return
+	}
+
+	if page.Header.CRCChecksum != page.Checksum() {
+		err = ErrBadChecksum
+	}
 
 	return
 }
 
+// BodySize returns the number of body bytes described by the segment table.
+func (p Page) BodySize() int {
+	size := 0
+	for _, lacing := range p.SegmentTable {
+		size += int(lacing)
+	}
+
+	return size
+}
+
 func (p PageHeader) Serialize() []byte {
 	var buf bytes.Buffer
 	buf.Grow(27)
@@ -108,11 +146,28 @@ func (p PageHeader) Serialize() []byte {
 	return buf.Bytes()
 }
 
+// Checksum computes the CRC-32 of the page, taken over the header with its CRCChecksum field set to 0, the segment
+// table and the body.
+func (p Page) Checksum() uint32 {
+	header := p.Header
+	header.CRCChecksum = 0
+
+	crc := crcUpdate(0, header.Serialize())
+	crc = crcUpdate(crc, p.SegmentTable)
+
+	return crcUpdate(crc, p.Body)
+}
+
+// Serialize returns the encoded page, with the CRCChecksum field of its header replaced by the actual checksum.
 func (p Page) Serialize() []byte {
 	var buf bytes.Buffer
 
-	buf.Write(p.Header.Serialize())
+	header := p.Header
+	header.CRCChecksum = p.Checksum()
+
+	buf.Write(header.Serialize())
 	buf.Write(p.SegmentTable)
+	buf.Write(p.Body)
 
 	return buf.Bytes()
 }
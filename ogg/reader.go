@@ -0,0 +1,175 @@
+package ogg
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Mode determines how corrupted data in a stream is handled.
+type Mode int
+
+const (
+	// Strict fails on the first page that is corrupted or doesn't start with the capture pattern.
+	Strict Mode = iota
+	// Lenient skips ahead to the next valid page whenever a page is corrupted or garbage is found between pages.
+	Lenient
+)
+
+func (m Mode) String() string {
+	return []string{"Strict", "Lenient"}[m]
+}
+
+// PageReader reads pages from a stream and verifies their checksums. Unlike ReadPage, it is able to resynchronize on
+// the next page when running in Lenient mode. It buffers data from the underlying reader.
+type PageReader struct {
+	r    *bufio.Reader
+	mode Mode
+
+	skipped int64
+	read    bool
+}
+
+// NewPageReader creates a PageReader reading pages from r.
+func NewPageReader(r io.Reader, mode Mode) *PageReader {
+	return &PageReader{
+		r:    bufio.NewReaderSize(r, MaxPageSize),
+		mode: mode,
+	}
+}
+
+// ReadPage returns the next page. In Strict mode, ErrInvalid is returned if the capture pattern isn't found where a
+// page should start, and ErrBadChecksum if the page is corrupted. In Lenient mode, such data is skipped until a valid
+// page is found. If the stream ends without any valid page being found, ErrInvalid is returned.
+func (pr *PageReader) ReadPage() (Page, error) {
+	for {
+		size, err := pr.nextPageSize()
+		if err != nil {
+			if err == io.EOF && pr.skipped > 0 && !pr.read {
+				return Page{}, ErrInvalid
+			}
+			return Page{}, err
+		}
+
+		b, err := pr.peek(size)
+		if err != nil {
+			if pr.mode == Lenient && err == io.EOF {
+				// A page cut short at the end of the stream is skipped like any other garbage.
+				pr.discard(1)
+				continue
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Page{}, err
+		}
+
+		page := parsePage(b)
+		if page.Header.CRCChecksum != page.Checksum() {
+			if pr.mode == Strict {
+				return page, ErrBadChecksum
+			}
+			// The capture pattern may have been part of another page's data, so the search continues right after it.
+			pr.discard(1)
+			continue
+		}
+
+		_, _ = pr.r.Discard(size)
+		pr.read = true
+
+		return page, nil
+	}
+}
+
+// Skipped returns the number of bytes that have been skipped while looking for valid pages.
+func (pr *PageReader) Skipped() int64 {
+	return pr.skipped
+}
+
+// nextPageSize finds the start of the next page and returns its total size. The page isn't consumed.
+func (pr *PageReader) nextPageSize() (int, error) {
+	for {
+		header, err := pr.peek(headerSize)
+		if err == io.EOF && len(header) == 0 {
+			return 0, io.EOF
+		}
+
+		var table []byte
+		if err == nil {
+			if !bytes.HasPrefix(header, CapturePattern[:]) {
+				err = ErrInvalid
+			} else {
+				table, err = pr.peek(headerSize + int(header[headerSize-1]))
+			}
+		}
+
+		if err == nil {
+			size := len(table)
+			for _, lacing := range table[headerSize:] {
+				size += int(lacing)
+			}
+			return size, nil
+		}
+
+		if pr.mode == Strict || (err != io.EOF && err != ErrInvalid) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+
+		pr.skip()
+	}
+}
+
+// skip discards data up to the next occurrence of the capture pattern, or everything buffered if there is none.
+func (pr *PageReader) skip() {
+	b, _ := pr.peek(pr.r.Buffered())
+
+	i := bytes.Index(b[1:], CapturePattern[:])
+	if i < 0 {
+		// Keep the end of the buffer, which may hold the beginning of the capture pattern.
+		pr.discard(max(1, len(b)-len(CapturePattern)+1))
+		return
+	}
+
+	pr.discard(i + 1)
+}
+
+// peek returns the next n bytes without consuming them. Readers that temporarily return no data, like network streams
+// that are waiting on more data, are retried instead of failing with io.ErrNoProgress.
+func (pr *PageReader) peek(n int) ([]byte, error) {
+	for {
+		b, err := pr.r.Peek(n)
+		if err != io.ErrNoProgress {
+			return b, err
+		}
+	}
+}
+
+func (pr *PageReader) discard(n int) {
+	discarded, _ := pr.r.Discard(n)
+	pr.skipped += int64(discarded)
+}
+
+// parsePage decodes a complete page from b. The returned page doesn't share memory with b.
+func parsePage(b []byte) Page {
+	page := Page{
+		Header: PageHeader{
+			StreamStructureVersion: b[4],
+			HeaderTypeFlag:         b[5],
+			GranulePosition:        int64(ByteOrder.Uint64(b[6:14])),
+			BitstreamSerialNumber:  ByteOrder.Uint32(b[14:18]),
+			PageSequenceNumber:     ByteOrder.Uint32(b[18:22]),
+			CRCChecksum:            ByteOrder.Uint32(b[22:26]),
+			NumberPageSegments:     b[26],
+		},
+	}
+	copy(page.Header.CapturePattern[:], b[:4])
+
+	segments := int(page.Header.NumberPageSegments)
+	page.SegmentTable = append([]byte(nil), b[headerSize:headerSize+segments]...)
+	page.Body = append([]byte(nil), b[headerSize+segments:]...)
+
+	return page
+}
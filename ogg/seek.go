@@ -0,0 +1,153 @@
+package ogg
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// SeekTime moves the decoder to the packet playing at t, like SeekGranule. ErrUnknownCodec is returned if the stream's
+// codec doesn't have a known granule rate.
+func (d *Decoder) SeekTime(t time.Duration) error {
+	if _, ok := d.r.(io.ReadSeeker); !ok {
+		return ErrNotSeekable
+	}
+
+	// The identification header holds the granule rate.
+	if err := d.readHeaders(1); err != nil {
+		return err
+	}
+
+	if d.clock.rate == 0 {
+		return ErrUnknownCodec
+	}
+
+	return d.SeekGranule(d.clock.Granule(t))
+}
+
+// SeekGranule moves the decoder to the first packet of the selected stream that ends after granule. The stream is
+// searched by bisection over its page headers, which requires the reader passed to Open to be an io.ReadSeeker;
+// ErrNotSeekable is returned otherwise. Header packets that haven't been returned yet are still returned before the
+// first audio packet. Chained streams aren't supported, as granule positions start over with every link.
+func (d *Decoder) SeekGranule(granule int64) error {
+	rs, ok := d.r.(io.ReadSeeker)
+	if !ok || d.pages == nil {
+		return ErrNotSeekable
+	}
+
+	// Only keep pending packets that are headers, anything else is from before the new position.
+	if d.packets > d.clock.headers {
+		d.pending = nil
+	}
+	if err := d.readHeaders(math.MaxInt); err != nil {
+		return err
+	}
+	serial := d.selected
+
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	// Narrow down the range that holds the last page before granule, until it is small enough to be read in one go.
+	lo, hi := int64(0), size
+	for hi-lo > MaxPageSize {
+		mid := lo + (hi-lo)/2
+
+		found, pageGranule, err := granuleAfter(rs, mid, serial)
+		if err != nil {
+			return err
+		}
+
+		if found && isBefore(pageGranule, granule) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	offset, lastGranule, err := lastPageBefore(rs, lo, serial, granule)
+	if err != nil {
+		return err
+	}
+
+	if _, err = rs.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	d.pages = NewPageReader(rs, d.mode)
+	d.currentPage = nil
+	d.lastSegment = 0
+	d.bodyOffset = 0
+	d.skipUntil = 0
+	d.granule = lastGranule
+	// The page at offset may start with the rest of a packet that ended before granule.
+	d.dropContinuation = true
+	delete(d.sequences, serial)
+
+	return nil
+}
+
+// granuleAfter returns the granule position of the first page of the stream with the given serial number that starts
+// at or after offset and finishes a packet. found is false if there is no such page.
+func granuleAfter(rs io.ReadSeeker, offset int64, serial uint32) (found bool, granule int64, err error) {
+	if _, err = rs.Seek(offset, io.SeekStart); err != nil {
+		return false, 0, err
+	}
+
+	// The offset is most likely in the middle of a page, so the reader has to resynchronize.
+	pr := NewPageReader(rs, Lenient)
+	for {
+		page, err := pr.ReadPage()
+		if err == io.EOF || err == ErrInvalid {
+			return false, 0, nil
+		} else if err != nil {
+			return false, 0, err
+		}
+
+		if page.Header.BitstreamSerialNumber == serial && page.Header.GranulePosition >= 0 {
+			return true, page.Header.GranulePosition, nil
+		}
+	}
+}
+
+// lastPageBefore reads the pages starting at or after offset and returns the offset right after the last page of the
+// stream with the given serial number that ends before granule, along with that page's granule position. offset and 0
+// are returned if there is no such page.
+func lastPageBefore(rs io.ReadSeeker, offset int64, serial uint32, granule int64) (int64, int64, error) {
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	pr := NewPageReader(rs, Lenient)
+	end, lastGranule := offset, int64(0)
+	var read int64
+
+	for {
+		page, err := pr.ReadPage()
+		if err == io.EOF || err == ErrInvalid {
+			return end, lastGranule, nil
+		} else if err != nil {
+			return 0, 0, err
+		}
+
+		read += int64(headerSize + len(page.SegmentTable) + len(page.Body))
+		pageEnd := offset + pr.Skipped() + read
+
+		if page.Header.BitstreamSerialNumber != serial || page.Header.GranulePosition < 0 {
+			continue
+		}
+
+		if !isBefore(page.Header.GranulePosition, granule) {
+			return end, lastGranule, nil
+		}
+
+		end, lastGranule = pageEnd, page.Header.GranulePosition
+	}
+}
+
+// isBefore reports whether a page with the given granule position ends before target. Header pages have a granule
+// position of 0 and always come first.
+func isBefore(pageGranule int64, target int64) bool {
+	return pageGranule == 0 || pageGranule < target
+}
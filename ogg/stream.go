@@ -0,0 +1,138 @@
+package ogg
+
+// Stream describes a logical bitstream found in an ogg stream. Multiplexed streams run in parallel, while chained
+// streams follow each other, forming links of a chain.
+type Stream struct {
+	// Serial is the bitstream serial number of the stream.
+	Serial uint32
+	// Codec is "opus" or "vorbis" if the stream's identification header was recognized, or "" otherwise.
+	Codec string
+	// Chain is the index of the chain link the stream belongs to.
+	Chain int
+	// Ended is set once the stream's last page has been read.
+	Ended bool
+}
+
+// Streams returns all logical bitstreams discovered so far, in the order they were found.
+func (d *Decoder) Streams() []Stream {
+	streams := make([]Stream, len(d.streams))
+	for i, stream := range d.streams {
+		streams[i] = *stream
+	}
+
+	return streams
+}
+
+// Selected returns the serial number of the stream whose packets are returned. ok is false if no stream has been
+// found yet.
+func (d *Decoder) Selected() (serial uint32, ok bool) {
+	return d.selected, d.hasSelected
+}
+
+// SelectStream makes the decoder return packets of the stream with the given serial number from here on. By default,
+// the first stream of every chain link is selected. Header packets of the stream that have already been passed are not
+// returned again, so streams should be selected before reading their packets, e.g. right after their beginning of
+// stream page shows up in Streams.
+func (d *Decoder) SelectStream(serial uint32) {
+	if d.hasSelected && d.selected == serial {
+		return
+	}
+
+	d.selected = serial
+	d.hasSelected = true
+	d.resetPacketState()
+	// A packet continued from an earlier page of the newly selected stream can't be completed.
+	d.dropContinuation = true
+}
+
+// MetadataChanged reports whether a new chain link has started since the last call. Chained streams are used by
+// internet radio to change metadata between songs, so the stream's headers should be parsed again when this is true.
+func (d *Decoder) MetadataChanged() bool {
+	changed := d.metadataChanged
+	d.metadataChanged = false
+
+	return changed
+}
+
+// Chain returns the index of the chain link currently being read.
+func (d *Decoder) Chain() int {
+	return d.chain
+}
+
+// track records the stream page belongs to, detecting new chain links. It reports whether page belongs to the
+// selected stream.
+func (d *Decoder) track(page *Page) bool {
+	serial := page.Header.BitstreamSerialNumber
+
+	if d.streamsBySerial == nil {
+		d.streamsBySerial = make(map[uint32]*Stream)
+	}
+
+	stream, ok := d.streamsBySerial[serial]
+	if !ok {
+		// A stream starting after all streams of the current link have ended begins a new link.
+		newLink := len(d.streams) > 0
+		for _, s := range d.streams {
+			if s.Chain == d.chain && !s.Ended {
+				newLink = false
+				break
+			}
+		}
+
+		if newLink {
+			d.chain++
+			// Position continues from where the previous link ended.
+			d.chainOffset += d.clock.Duration(d.granule)
+			d.hasSelected = false
+			d.metadataChanged = true
+		}
+
+		stream = &Stream{Serial: serial, Chain: d.chain}
+		if page.Header.HeaderTypeFlag&BeginningOfStream != 0 {
+			if c, ok := detectClock(firstPacket(page)); ok {
+				stream.Codec = c.codec
+			}
+		}
+
+		d.streams = append(d.streams, stream)
+		d.streamsBySerial[serial] = stream
+
+		if !d.hasSelected {
+			d.selected = serial
+			d.hasSelected = true
+			d.resetPacketState()
+		}
+	}
+
+	if page.Header.HeaderTypeFlag&EndOfStream != 0 {
+		stream.Ended = true
+	}
+
+	return serial == d.selected
+}
+
+// resetPacketState forgets everything about the packets of the previously selected stream.
+func (d *Decoder) resetPacketState() {
+	d.currentPage = nil
+	d.lastSegment = 0
+	d.bodyOffset = 0
+	d.pending = nil
+	d.clock = clock{}
+	d.firstRead = false
+	d.headers = nil
+	d.packets = 0
+	d.granule = 0
+}
+
+// firstPacket returns the first packet that starts on page, or whatever part of it the page holds.
+func firstPacket(page *Page) []byte {
+	size := 0
+	for _, lacing := range page.SegmentTable {
+		size += int(lacing)
+		if lacing < 255 {
+			break
+		}
+	}
+
+	return page.Body[:min(size, len(page.Body))]
+}
@@ -0,0 +1,125 @@
+// Package vorbis parses the identification and comment headers that start every Ogg Vorbis stream.
+//
+// Implementation spec: https://xiph.org/vorbis/doc/Vorbis_I_spec.html
+package vorbis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/olympus-go/apollo/ogg"
+	"github.com/olympus-go/apollo/ogg/comment"
+)
+
+var ErrInvalidIdentification = errors.New("invalid vorbis identification header")
+var ErrInvalidComment = errors.New("invalid vorbis comment header")
+
+// Header packet types.
+const (
+	identificationType byte = 0x01
+	commentType        byte = 0x03
+)
+
+var magic = []byte("vorbis")
+
+// Identification is the first header of a vorbis stream.
+type Identification struct {
+	Version    uint32
+	Channels   uint8
+	SampleRate uint32
+	// BitrateMaximum, BitrateNominal and BitrateMinimum are hints in bits per second. A value of 0 means the hint is
+	// unset.
+	BitrateMaximum int32
+	BitrateNominal int32
+	BitrateMinimum int32
+	// BlockSize0 and BlockSize1 are the short and long window sizes in samples.
+	BlockSize0 uint16
+	BlockSize1 uint16
+}
+
+// IsIdentification reports whether packet looks like a vorbis identification header.
+func IsIdentification(packet []byte) bool {
+	return isHeader(packet, identificationType)
+}
+
+// ParseIdentification parses a vorbis identification header packet.
+func ParseIdentification(packet []byte) (Identification, error) {
+	if len(packet) < 30 || !IsIdentification(packet) {
+		return Identification{}, ErrInvalidIdentification
+	}
+
+	id := Identification{
+		Version:        binary.LittleEndian.Uint32(packet[7:11]),
+		Channels:       packet[11],
+		SampleRate:     binary.LittleEndian.Uint32(packet[12:16]),
+		BitrateMaximum: int32(binary.LittleEndian.Uint32(packet[16:20])),
+		BitrateNominal: int32(binary.LittleEndian.Uint32(packet[20:24])),
+		BitrateMinimum: int32(binary.LittleEndian.Uint32(packet[24:28])),
+		BlockSize0:     1 << (packet[28] & 0x0f),
+		BlockSize1:     1 << (packet[28] >> 4),
+	}
+
+	// The framing bit has to be set.
+	if id.Version != 0 || id.Channels == 0 || id.SampleRate == 0 || id.BlockSize0 > id.BlockSize1 || packet[29]&1 == 0 {
+		return Identification{}, ErrInvalidIdentification
+	}
+
+	return id, nil
+}
+
+// Comment is the second header of a vorbis stream.
+type Comment = comment.Header
+
+// IsComment reports whether packet looks like a vorbis comment header.
+func IsComment(packet []byte) bool {
+	return isHeader(packet, commentType)
+}
+
+// ParseComment parses a vorbis comment header packet.
+func ParseComment(packet []byte) (Comment, error) {
+	if !IsComment(packet) {
+		return Comment{}, ErrInvalidComment
+	}
+	c, r, err := comment.Parse(packet[1+len(magic):])
+	if err != nil {
+		return Comment{}, ErrInvalidComment
+	}
+
+	// The framing bit has to be set.
+	if len(r) == 0 || r[0]&1 == 0 {
+		return Comment{}, ErrInvalidComment
+	}
+
+	return c, nil
+}
+
+// ReadHeaders reads the identification and comment headers from the beginning of the ogg stream in r.
+func ReadHeaders(r io.Reader) (Identification, Comment, error) {
+	d := ogg.NewDecoder()
+	_ = d.Open(r)
+
+	packet, err := d.Next()
+	if err != nil {
+		return Identification{}, Comment{}, err
+	}
+	id, err := ParseIdentification(packet)
+	if err != nil {
+		return Identification{}, Comment{}, err
+	}
+
+	if packet, err = d.Next(); err != nil {
+		return Identification{}, Comment{}, err
+	}
+	comment, err := ParseComment(packet)
+	if err != nil {
+		return Identification{}, Comment{}, err
+	}
+
+	return id, comment, nil
+}
+
+func isHeader(packet []byte, typ byte) bool {
+	return len(packet) > len(magic) && packet[0] == typ && bytes.Equal(packet[1:1+len(magic)], magic)
+}
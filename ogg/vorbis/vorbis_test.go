@@ -0,0 +1,131 @@
+package vorbis_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/olympus-go/apollo/ogg"
+	"github.com/olympus-go/apollo/ogg/vorbis"
+)
+
+func TestParseIdentification(t *testing.T) {
+	type test struct {
+		packet []byte
+		id     vorbis.Identification
+		err    error
+	}
+
+	valid := genIdentification(2, 44100, 160000, 0xb8)
+	noFraming := append(genIdentification(2, 44100, 160000, 0xb8)[:29], 0)
+	invalid := vorbis.ErrInvalidIdentification
+
+	tests := map[string]test{
+		"valid": {valid, vorbis.Identification{
+			Channels: 2, SampleRate: 44100, BitrateNominal: 160000, BlockSize0: 256, BlockSize1: 2048,
+		}, nil},
+		"short":           {valid[:29], vorbis.Identification{}, invalid},
+		"no_channels":     {genIdentification(0, 44100, 160000, 0xb8), vorbis.Identification{}, invalid},
+		"no_sample_rate":  {genIdentification(2, 0, 160000, 0xb8), vorbis.Identification{}, invalid},
+		"bad_block_sizes": {genIdentification(2, 44100, 160000, 0x8b), vorbis.Identification{}, invalid},
+		"no_framing_bit":  {noFraming, vorbis.Identification{}, invalid},
+		"comment_header":  {genComment("vendor"), vorbis.Identification{}, invalid},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			id, err := vorbis.ParseIdentification(tst.packet)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			}
+			if id != tst.id {
+				t.Fatalf("expected %+v; got %+v", tst.id, id)
+			}
+		})
+	}
+}
+
+func TestParseComment(t *testing.T) {
+	type test struct {
+		packet  []byte
+		comment vorbis.Comment
+		err     error
+	}
+
+	valid := genComment("Xiph.Org libVorbis I 20200704 (Reducing Environment)", "TITLE=Song", "ARTIST=Someone")
+
+	tests := map[string]test{
+		"valid": {valid, vorbis.Comment{
+			Vendor:   "Xiph.Org libVorbis I 20200704 (Reducing Environment)",
+			Comments: []string{"TITLE=Song", "ARTIST=Someone"},
+		}, nil},
+		"no_comments":    {genComment(""), vorbis.Comment{Vendor: "", Comments: []string{}}, nil},
+		"no_framing_bit": {valid[:len(valid)-1], vorbis.Comment{}, vorbis.ErrInvalidComment},
+		"truncated":      {valid[:len(valid)-2], vorbis.Comment{}, vorbis.ErrInvalidComment},
+		"identification": {genIdentification(2, 44100, 0, 0xb8), vorbis.Comment{}, vorbis.ErrInvalidComment},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			comment, err := vorbis.ParseComment(tst.packet)
+			if !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			}
+			if !reflect.DeepEqual(comment, tst.comment) {
+				t.Fatalf("expected %+v; got %+v", tst.comment, comment)
+			}
+		})
+	}
+}
+
+func TestReadHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	e := ogg.NewEncoder(&buf, 1)
+	_ = e.WritePacket(genIdentification(2, 48000, 96000, 0xb8), 0)
+	_ = e.Flush()
+	_ = e.WritePacket(genComment("vendor", "album=Album", "Album=Other"), 0)
+	_ = e.WritePacket([]byte{0x05, 'v', 'o', 'r', 'b', 'i', 's'}, 0)
+	_ = e.Close()
+
+	id, comment, err := vorbis.ReadHeaders(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id.SampleRate != 48000 || id.Channels != 2 || id.BitrateNominal != 96000 {
+		t.Fatalf("unexpected identification %+v", id)
+	}
+	if album, ok := comment.Get("ALBUM"); !ok || album != "Album" {
+		t.Fatalf("expected album Album; got %q", album)
+	}
+	if album := comment.Map()["album"]; album != "Album;Other" {
+		t.Fatalf("expected albums Album;Other; got %q", album)
+	}
+}
+
+func genIdentification(channels uint8, sampleRate uint32, bitrate int32, blockSizes byte) []byte {
+	packet := append([]byte{0x01}, "vorbis"...)
+	packet = binary.LittleEndian.AppendUint32(packet, 0)
+	packet = append(packet, channels)
+	packet = binary.LittleEndian.AppendUint32(packet, sampleRate)
+	packet = binary.LittleEndian.AppendUint32(packet, 0)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(bitrate))
+	packet = binary.LittleEndian.AppendUint32(packet, 0)
+
+	return append(packet, blockSizes, 1)
+}
+
+func genComment(vendor string, comments ...string) []byte {
+	packet := append([]byte{0x03}, "vorbis"...)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(vendor)))
+	packet = append(packet, vendor...)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(comments)))
+	for _, comment := range comments {
+		packet = binary.LittleEndian.AppendUint32(packet, uint32(len(comment)))
+		packet = append(packet, comment...)
+	}
+
+	return append(packet, 1)
+}
@@ -0,0 +1,85 @@
+package apollo
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultJitterBuffer is how far ahead of play speed a paced Player sends audio, unless configured otherwise.
+const DefaultJitterBuffer = 100 * time.Millisecond
+
+// pacer releases audio to the out channel at play speed. It keeps a clock of when each bit of media is due, and lets
+// audio through up to buffer ahead of it.
+type pacer struct {
+	buffer time.Duration
+
+	// start is the wall time the clock was started at, or zero if it has to be restarted, and released is the media
+	// time sent since.
+	start    time.Time
+	released time.Duration
+
+	underruns atomic.Int64
+	overruns  atomic.Int64
+}
+
+func newPacer(buffer time.Duration) *pacer {
+	if buffer == 0 {
+		buffer = DefaultJitterBuffer
+	}
+
+	return &pacer{buffer: max(buffer, 0)}
+}
+
+// reset restarts the clock with the next packet. It is used wherever a gap in the audio is expected, e.g. when pausing.
+func (pc *pacer) reset() {
+	pc.start = time.Time{}
+	pc.released = 0
+}
+
+// wait blocks until audio can be released without getting more than buffer ahead of the clock. If everything released
+// so far has already played out, the consumer ran dry, which counts as an underrun and restarts the clock. It returns
+// false if ctx is done first.
+func (pc *pacer) wait(ctx context.Context) bool {
+	now := time.Now()
+	if pc.start.IsZero() {
+		pc.start = now
+	} else if now.After(pc.start.Add(pc.released)) {
+		pc.underruns.Add(1)
+		pc.start = now.Add(-pc.released)
+	}
+
+	return sleep(ctx, time.Until(pc.start.Add(pc.released-pc.buffer)))
+}
+
+// release records that d worth of audio was sent after the consumer took blocked to accept it. If the consumer took so
+// long that the buffered audio played out meanwhile, it couldn't keep up, which counts as an overrun and restarts the
+// clock from the audio just sent.
+func (pc *pacer) release(d time.Duration, blocked time.Duration) {
+	if blocked > pc.buffer {
+		pc.overruns.Add(1)
+		pc.start = time.Now().Add(-pc.released)
+	}
+
+	pc.released += d
+}
+
+// pace sends v on ch once its d worth of audio is due, and accounts for it. Without a pacer, or for audio of unknown
+// length, v is sent right away. It returns false if ctx is done first.
+func pace[T any](ctx context.Context, pc *pacer, ch chan<- T, v T, d time.Duration) bool {
+	if pc == nil || d <= 0 {
+		return send(ctx, ch, v)
+	}
+
+	if !pc.wait(ctx) {
+		return false
+	}
+
+	sent := time.Now()
+	if !send(ctx, ch, v) {
+		return false
+	}
+	pc.release(d, time.Since(sent))
+
+	return true
+}
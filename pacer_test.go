@@ -0,0 +1,134 @@
+package apollo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestPace(t *testing.T) {
+	type test struct {
+		paced    bool
+		buffer   time.Duration
+		duration time.Duration
+		// minimum is the least time sending 5 packets may take.
+		minimum time.Duration
+	}
+
+	ms := time.Millisecond
+
+	tests := map[string]test{
+		"unpaced":        {paced: false, duration: 20 * ms, minimum: 0},
+		"unknown_length": {paced: true, buffer: -1, duration: 0, minimum: 0},
+		"realtime":       {paced: true, buffer: -1, duration: 20 * ms, minimum: 80 * ms},
+		"jitter_buffer":  {paced: true, buffer: 40 * ms, duration: 20 * ms, minimum: 40 * ms},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			pc := apollo.NewPacer(tst.buffer)
+			if !tst.paced {
+				pc = nil
+			}
+
+			ch := make(chan []byte, 5)
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				if !apollo.Pace(context.Background(), pc, ch, []byte{byte(i)}, tst.duration) {
+					t.Fatal("expected packet to be sent")
+				}
+			}
+
+			if elapsed := time.Since(start); elapsed < tst.minimum {
+				t.Fatalf("expected sending to take at least %s; got %s", tst.minimum, elapsed)
+			}
+			// Audio that can be sent right away shouldn't wait for anything.
+			if elapsed := time.Since(start); tst.minimum == 0 && elapsed > 50*time.Millisecond {
+				t.Fatalf("expected sending to be immediate; got %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestPace_Cancel(t *testing.T) {
+	pc := apollo.NewPacer(-1)
+	ch := make(chan []byte, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_ = apollo.Pace(ctx, pc, ch, nil, time.Second)
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	if apollo.Pace(ctx, pc, ch, nil, time.Second) {
+		t.Fatal("expected pacing to stop once the context is done")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected pacing to stop right away; got %s", elapsed)
+	}
+}
+
+func TestPacer_Underruns(t *testing.T) {
+	type test struct {
+		// gap is how long the producer waits before sending the second packet.
+		gap       time.Duration
+		reset     bool
+		underruns int64
+	}
+
+	tests := map[string]test{
+		"on_time": {gap: 0, underruns: 0},
+		"late":    {gap: 50 * time.Millisecond, underruns: 1},
+		"reset":   {gap: 50 * time.Millisecond, reset: true, underruns: 0},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			pc := apollo.NewPacer(-1)
+			ch := make(chan []byte, 2)
+
+			_ = apollo.Pace(context.Background(), pc, ch, nil, 20*time.Millisecond)
+			time.Sleep(tst.gap)
+			if tst.reset {
+				pc.Reset()
+			}
+			_ = apollo.Pace(context.Background(), pc, ch, nil, 20*time.Millisecond)
+
+			if pc.Underruns() != tst.underruns {
+				t.Fatalf("expected %d underruns; got %d", tst.underruns, pc.Underruns())
+			}
+		})
+	}
+}
+
+func TestPacer_Overruns(t *testing.T) {
+	type test struct {
+		// delay is how long the consumer takes to accept a packet.
+		delay    time.Duration
+		overruns int64
+	}
+
+	tests := map[string]test{
+		"keeping_up": {delay: 0, overruns: 0},
+		"blocked":    {delay: 50 * time.Millisecond, overruns: 1},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			pc := apollo.NewPacer(10 * time.Millisecond)
+			ch := make(chan []byte)
+
+			go func() {
+				time.Sleep(tst.delay)
+				<-ch
+			}()
+
+			_ = apollo.Pace(context.Background(), pc, ch, nil, 20*time.Millisecond)
+
+			if pc.Overruns() != tst.overruns {
+				t.Fatalf("expected %d overruns; got %d", tst.overruns, pc.Overruns())
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package apollo
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/olympus-go/apollo/ogg/opus"
+)
+
+// DefaultSilenceFrames is the number of silence frames a Packetizer sends when its input pauses, unless configured
+// otherwise.
+const DefaultSilenceFrames = 5
+
+// maxLag is how far a paced Packetizer can fall behind before it stops catching up and restarts its clock.
+const maxLag = 100 * time.Millisecond
+
+// Packet is a single opus packet, as sent by a Packetizer.
+type Packet struct {
+	Data []byte
+	// Sequence increases by one with every packet and wraps around, like an RTP sequence number.
+	Sequence uint16
+	// Timestamp is the position of the packet in 48kHz samples and wraps around, like an RTP timestamp.
+	Timestamp uint32
+	// Duration is the length of the audio in the packet.
+	Duration time.Duration
+}
+
+type PacketizerConfig struct {
+	// Realtime paces packets, so that each one is sent once the previous one would have finished playing. Packets are
+	// otherwise sent as fast as they are consumed.
+	// Defaults to false.
+	Realtime bool `json:"realtime"`
+
+	// SilenceFrames sets how many silence frames are sent when the input pauses, so that the receiving end doesn't
+	// interpolate audio that isn't coming. The input is considered paused once no packet arrives within twice the
+	// duration of the last one. Negative values disable silence frames.
+	// Defaults to DefaultSilenceFrames.
+	SilenceFrames int `json:"silence_frames"`
+
+	// Silence sets the packet sent as a silence frame.
+	// Defaults to opus.SilenceFrame.
+	Silence []byte `json:"silence"`
+}
+
+// Packetizer turns a channel of opus packets, such as Player.Out with PlayerConfig.OpusPackets set, into a stream of
+// Packets that each hold exactly one opus packet, along with the timing information voice transports need. OpusHead
+// and OpusTags packets are skipped. Any other message that isn't a valid opus packet is logged and dropped.
+type Packetizer struct {
+	in     <-chan []byte
+	out    chan Packet
+	config PacketizerConfig
+	logger *slog.Logger
+
+	sequence  uint16
+	timestamp uint32
+	// next is when the next packet is due when pacing, or zero if the clock has to be restarted.
+	next time.Time
+
+	dropped atomic.Int64
+}
+
+// NewPacketizer creates a Packetizer reading from in. It runs until in is closed. If no logging is desired, nil can be
+// passed in for h.
+func NewPacketizer(in <-chan []byte, config PacketizerConfig, h slog.Handler) *Packetizer {
+	return NewPacketizerContext(context.Background(), in, config, h)
+}
+
+// NewPacketizerContext creates a Packetizer reading from in. It runs until in is closed or ctx is done.
+func NewPacketizerContext(ctx context.Context, in <-chan []byte, config PacketizerConfig, h slog.Handler) *Packetizer {
+	if h == nil {
+		h = nopLogHandler{}
+	}
+	if config.SilenceFrames == 0 {
+		config.SilenceFrames = DefaultSilenceFrames
+	}
+	if len(config.Silence) == 0 {
+		config.Silence = opus.SilenceFrame
+	}
+
+	pk := &Packetizer{
+		in:     in,
+		out:    make(chan Packet),
+		config: config,
+		logger: slog.New(h),
+	}
+
+	go pk.run(ctx)
+
+	return pk
+}
+
+// Out returns the channel Packets are sent on. It is closed once the Packetizer stops.
+func (pk *Packetizer) Out() <-chan Packet {
+	return pk.out
+}
+
+// Dropped returns the number of input messages that were dropped because they weren't valid opus packets.
+func (pk *Packetizer) Dropped() int64 {
+	return pk.dropped.Load()
+}
+
+func (pk *Packetizer) run(ctx context.Context) {
+	defer close(pk.out)
+
+	idle := time.NewTimer(0)
+	stopTimer(idle)
+	// idleChan is only set while waiting for the input to continue, so that silence is sent once per pause.
+	var idleChan <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-idleChan:
+			idleChan = nil
+			if !pk.sendSilence(ctx) {
+				return
+			}
+		case data, ok := <-pk.in:
+			if !ok {
+				return
+			}
+
+			duration, header, err := opusPacket(data)
+			if header {
+				continue
+			}
+			if err != nil {
+				pk.dropped.Add(1)
+				pk.logger.Warn("dropping message that isn't an opus packet",
+					slog.String("error", err.Error()),
+					slog.Int("size", len(data)),
+				)
+				continue
+			}
+
+			if !pk.send(ctx, data, duration) {
+				return
+			}
+
+			stopTimer(idle)
+			idle.Reset(2 * duration)
+			idleChan = idle.C
+		}
+	}
+}
+
+// sendSilence sends the configured number of silence frames and restarts the pacing clock, since the input paused.
+func (pk *Packetizer) sendSilence(ctx context.Context) bool {
+	// The pause started a frame ago, so the silence can't be on time anyway.
+	pk.next = time.Time{}
+	if pk.config.SilenceFrames < 0 {
+		return true
+	}
+
+	duration, err := opus.PacketDuration(pk.config.Silence)
+	if err != nil {
+		duration = 20 * time.Millisecond
+	}
+
+	for i := 0; i < pk.config.SilenceFrames; i++ {
+		if !pk.send(ctx, pk.config.Silence, duration) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// send waits until the packet is due if pacing, and sends it. It returns false if ctx is done.
+func (pk *Packetizer) send(ctx context.Context, data []byte, duration time.Duration) bool {
+	if pk.config.Realtime {
+		now := time.Now()
+		if pk.next.IsZero() || now.Sub(pk.next) > maxLag {
+			pk.next = now
+		}
+
+		if !sleep(ctx, time.Until(pk.next)) {
+			return false
+		}
+		pk.next = pk.next.Add(duration)
+	}
+
+	packet := Packet{Data: data, Sequence: pk.sequence, Timestamp: pk.timestamp, Duration: duration}
+	pk.sequence++
+	pk.timestamp += uint32(duration * opus.SampleRate / time.Second)
+
+	return send(ctx, pk.out, packet)
+}
+
+// opusPacket returns the duration of packet. header is set for the OpusHead and OpusTags packets that start Ogg Opus
+// streams, which don't hold any audio. An error is returned if packet is neither a header nor a valid opus packet.
+func opusPacket(packet []byte) (duration time.Duration, header bool, err error) {
+	if opus.IsHead(packet) || opus.IsTags(packet) {
+		return 0, true, nil
+	}
+
+	duration, err = opus.PacketDuration(packet)
+
+	return duration, false, err
+}
+
+// sleep waits for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// stopTimer stops t and drains its channel, so that it can be reset.
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
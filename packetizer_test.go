@@ -0,0 +1,135 @@
+package apollo_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/ogg/opus"
+)
+
+func TestPacketizer(t *testing.T) {
+	type test struct {
+		in      [][]byte
+		out     []apollo.Packet
+		dropped int64
+	}
+
+	celt20 := []byte{31 << 3, 1, 2}
+	celt40 := []byte{31<<3 | 1, 3, 4}
+
+	tests := map[string]test{
+		"packets": {
+			in: [][]byte{celt20, celt40, celt20},
+			out: []apollo.Packet{
+				{Data: celt20, Sequence: 0, Timestamp: 0, Duration: 20 * time.Millisecond},
+				{Data: celt40, Sequence: 1, Timestamp: 960, Duration: 40 * time.Millisecond},
+				{Data: celt20, Sequence: 2, Timestamp: 2880, Duration: 20 * time.Millisecond},
+			},
+		},
+		"headers": {
+			in: [][]byte{[]byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00"), []byte("OpusTags"), celt20},
+			out: []apollo.Packet{
+				{Data: celt20, Sequence: 0, Timestamp: 0, Duration: 20 * time.Millisecond},
+			},
+		},
+		"invalid": {
+			in: [][]byte{{}, celt20, {31<<3 | 3, 0}},
+			out: []apollo.Packet{
+				{Data: celt20, Sequence: 0, Timestamp: 0, Duration: 20 * time.Millisecond},
+			},
+			dropped: 2,
+		},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			in := make(chan []byte, len(tst.in))
+			for _, data := range tst.in {
+				in <- data
+			}
+			close(in)
+
+			pk := apollo.NewPacketizer(in, apollo.PacketizerConfig{}, nil)
+
+			var out []apollo.Packet
+			for packet := range pk.Out() {
+				out = append(out, packet)
+			}
+
+			if len(out) != len(tst.out) {
+				t.Fatalf("expected %d packets; got %d", len(tst.out), len(out))
+			}
+			for i := range out {
+				if !equalPackets(out[i], tst.out[i]) {
+					t.Fatalf("expected packet %d to be %+v; got %+v", i, tst.out[i], out[i])
+				}
+			}
+			if pk.Dropped() != tst.dropped {
+				t.Fatalf("expected %d dropped; got %d", tst.dropped, pk.Dropped())
+			}
+		})
+	}
+}
+
+func TestPacketizer_Silence(t *testing.T) {
+	type test struct {
+		silenceFrames int
+		expected      int
+	}
+
+	tests := map[string]test{
+		"default":  {silenceFrames: 0, expected: apollo.DefaultSilenceFrames},
+		"custom":   {silenceFrames: 2, expected: 2},
+		"disabled": {silenceFrames: -1, expected: 0},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			in := make(chan []byte)
+			pk := apollo.NewPacketizer(in, apollo.PacketizerConfig{SilenceFrames: tst.silenceFrames}, nil)
+
+			// The silence frames follow a pause of twice the packet's duration.
+			go func() {
+				in <- []byte{31 << 3}
+				time.Sleep(100 * time.Millisecond)
+				close(in)
+			}()
+
+			var silence int
+			for packet := range pk.Out() {
+				if bytes.Equal(packet.Data, opus.SilenceFrame) {
+					silence++
+				}
+			}
+
+			if silence != tst.expected {
+				t.Fatalf("expected %d silence frames; got %d", tst.expected, silence)
+			}
+		})
+	}
+}
+
+func TestPacketizer_Realtime(t *testing.T) {
+	in := make(chan []byte, 5)
+	for i := 0; i < 5; i++ {
+		in <- []byte{31 << 3}
+	}
+	close(in)
+
+	start := time.Now()
+	pk := apollo.NewPacketizer(in, apollo.PacketizerConfig{Realtime: true, SilenceFrames: -1}, nil)
+	for range pk.Out() {
+	}
+
+	// The first packet is sent right away, every following one 20ms after the previous one.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("expected packets to take at least 80ms; got %s", elapsed)
+	}
+}
+
+func equalPackets(a, b apollo.Packet) bool {
+	return bytes.Equal(a.Data, b.Data) && a.Sequence == b.Sequence && a.Timestamp == b.Timestamp &&
+		a.Duration == b.Duration
+}
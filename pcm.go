@@ -0,0 +1,90 @@
+package apollo
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// SampleFormat is the encoding of a single PCM sample.
+type SampleFormat string
+
+const (
+	// S16LE is signed 16-bit little endian integer samples. It is used when no SampleFormat is set.
+	S16LE SampleFormat = "s16le"
+	// F32LE is 32-bit little endian floating point samples, nominally between -1 and 1.
+	F32LE SampleFormat = "f32le"
+)
+
+// Size returns the size of one sample in bytes.
+func (s SampleFormat) Size() int {
+	if s == F32LE {
+		return 4
+	}
+
+	return 2
+}
+
+// load returns the sample at the start of p, scaled to [-1, 1].
+func (s SampleFormat) load(p []byte) float64 {
+	if s == F32LE {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(p)))
+	}
+
+	return float64(int16(binary.LittleEndian.Uint16(p))) / -math.MinInt16
+}
+
+// store writes v, scaled to [-1, 1], as the sample at the start of p. Integer samples that go out of range are clipped,
+// while floating point samples keep their headroom.
+func (s SampleFormat) store(p []byte, v float64) {
+	if s == F32LE {
+		binary.LittleEndian.PutUint32(p, math.Float32bits(float32(v)))
+		return
+	}
+
+	binary.LittleEndian.PutUint16(p, uint16(clamp16(v*-math.MinInt16)))
+}
+
+func (s SampleFormat) valid() bool {
+	return s == "" || s == S16LE || s == F32LE
+}
+
+// PCMFormat describes interleaved PCM audio.
+type PCMFormat struct {
+	SampleRate int `json:"sample_rate"`
+	Channels   int `json:"channels"`
+	// SampleFormat defaults to S16LE if empty.
+	SampleFormat SampleFormat `json:"sample_format,omitempty"`
+}
+
+// DefaultPCMFormat returns 48kHz stereo s16le, which is what opus encoders expect.
+func DefaultPCMFormat() PCMFormat {
+	return PCMFormat{
+		SampleRate:   48000,
+		Channels:     2,
+		SampleFormat: S16LE,
+	}
+}
+
+// BlockAlign returns the size in bytes of one sample across all channels.
+func (f PCMFormat) BlockAlign() int {
+	return f.Channels * f.SampleFormat.Size()
+}
+
+// Size returns the number of bytes needed to hold d worth of audio. The size is always a multiple of BlockAlign.
+func (f PCMFormat) Size(d time.Duration) int {
+	return int(int64(d)*int64(f.SampleRate)/int64(time.Second)) * f.BlockAlign()
+}
+
+// Duration returns the length of size bytes of audio.
+func (f PCMFormat) Duration(size int) time.Duration {
+	if f.SampleRate == 0 || f.Channels == 0 {
+		return 0
+	}
+
+	return time.Duration(int64(size/f.BlockAlign()) * int64(time.Second) / int64(f.SampleRate))
+}
+
+func (f PCMFormat) valid() bool {
+	return f.SampleRate > 0 && f.Channels > 0 && f.SampleFormat.valid()
+}
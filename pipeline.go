@@ -0,0 +1,264 @@
+package apollo
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// defaultFrameDuration is the amount of PCM read from a queue entry at a time when the PCM pipeline is in use, unless
+// PlayerConfig.FrameDuration is set.
+const defaultFrameDuration = 20 * time.Millisecond
+
+// pipeline is used instead of sending codec output straight to the out channel once an encoder has been set, or PCM
+// output is enabled. Queue entries are then expected to decode to PCM, which is passed through a Crossfader and written
+// into a single encoder that runs across Playables. The encoder's output is what ends up on the out channel. Without an
+// encoder, the PCM itself is sent in frames of a fixed size.
+type pipeline struct {
+	format  PCMFormat
+	encoder Codec
+	fader   *Crossfader
+	volume  *ramp
+	frame   []byte
+
+	// out holds PCM that doesn't fill a whole frame yet when there is no encoder.
+	out []byte
+
+	// offset and read track the position within the current Playable.
+	offset time.Duration
+	read   int
+
+	// frames feeds the running encoder session and is nil if there is none. done is closed once the last session has
+	// ended.
+	frames chan []byte
+	done   chan struct{}
+}
+
+func newPipeline(format PCMFormat, frameDuration time.Duration, crossfade time.Duration, encoder Codec,
+	volume float64) *pipeline {
+	if !format.valid() {
+		format = DefaultPCMFormat()
+	}
+	if format.Size(frameDuration) <= 0 {
+		frameDuration = defaultFrameDuration
+	}
+
+	return &pipeline{
+		format:  format,
+		encoder: encoder,
+		fader:   NewCrossfader(format, format.Size(crossfade)),
+		volume:  newRamp(format, volume),
+		frame:   make([]byte, format.Size(frameDuration)),
+	}
+}
+
+// end stops feeding the running encoder session, if any, which lets the encoder finish.
+func (pl *pipeline) end() {
+	if pl.frames != nil {
+		close(pl.frames)
+		pl.frames = nil
+	}
+}
+
+// position returns the media time reached in the current Playable.
+func (pl *pipeline) position() time.Duration {
+	return pl.offset + pl.format.Duration(pl.read)
+}
+
+// restart restarts position tracking at offset.
+func (pl *pipeline) restart(offset time.Duration) {
+	pl.offset = offset
+	pl.read = 0
+}
+
+// process reads the next frame of PCM from pc and sends it through the pipeline. io.EOF is returned once pc has been
+// read to the end, and context.Canceled if ctx was cancelled while sending.
+func (p *Player) process(ctx context.Context, pc PlayableCodec) error {
+	pl := p.pipe
+
+	n, err := io.ReadFull(pc.codec, pl.frame)
+	if err == io.ErrUnexpectedEOF {
+		n -= n % pl.format.BlockAlign()
+		err = io.EOF
+	}
+
+	if n > 0 {
+		pl.read += n
+		p.mediaPosition.Store(int64(pl.position()))
+		p.mediaTimed.Store(true)
+		p.checkMetadata(pc)
+		p.maybePrefetch(pc)
+
+		frame := make([]byte, n)
+		copy(frame, pl.frame[:n])
+		// The Playable's own gain goes before the crossfader, so that both sides of a fade are at their own level.
+		pl.format.SampleFormat.ApplyGain(frame, DecibelsToLinear(pc.gain+codecGain(pc.codec)))
+
+		if writeErr := p.writePCM(ctx, pl.fader.Write(frame)); writeErr != nil {
+			return writeErr
+		}
+		// The encoder's packets span Playables, so frames written are counted instead.
+		p.bytesSent.Add(1)
+	}
+
+	return err
+}
+
+// writePCM applies the master volume to pcm and hands it to the encoder session, starting one if none is running.
+// Without an encoder, pcm is sent on the out channel instead. Either way, pcm is paced if PlayerConfig.Realtime is set,
+// which in turn paces the encoder's output.
+func (p *Player) writePCM(ctx context.Context, pcm []byte) error {
+	if len(pcm) == 0 {
+		return nil
+	}
+
+	pl := p.pipe
+	pl.volume.apply(pcm, p.Volume())
+
+	if pl.encoder == nil {
+		return p.writeFrames(ctx, pcm)
+	}
+
+	if pl.frames == nil || isClosed(pl.done) {
+		pl.end()
+		if err := p.startEncoder(); err != nil {
+			return err
+		}
+	}
+
+	if !pace(ctx, p.pacer, pl.frames, pcm, pl.format.Duration(len(pcm))) {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+// writeFrames sends pcm on the out channel in frames of the pipeline's frame size. Whatever doesn't fill a whole frame
+// is kept for the next call.
+func (p *Player) writeFrames(ctx context.Context, pcm []byte) error {
+	pl := p.pipe
+	pl.out = append(pl.out, pcm...)
+
+	sent := 0
+	defer func() { pl.out = append(pl.out[:0], pl.out[sent:]...) }()
+
+	for len(pl.out)-sent >= len(pl.frame) {
+		frame := make([]byte, len(pl.frame))
+		copy(frame, pl.out[sent:])
+
+		if !pace(ctx, p.pacer, p.outChan, frame, pl.format.Duration(len(frame))) {
+			return context.Canceled
+		}
+		sent += len(frame)
+	}
+
+	return nil
+}
+
+// flushPipeline writes out everything held back by the crossfader and ends the encoder session, so that the encoder
+// releases any audio it buffered. It blocks until the encoder's output has been sent. Without an encoder, the last
+// frame is padded with silence instead.
+func (p *Player) flushPipeline() {
+	pl := p.pipe
+
+	if err := p.writePCM(p.ctx, pl.fader.Flush()); err != nil {
+		p.logger.Error("failed to flush pipeline", slog.String("error", err.Error()))
+	}
+
+	if pl.encoder == nil {
+		if len(pl.out) > 0 {
+			// Zeroes are silence in every sample format.
+			_ = p.writeFrames(p.ctx, make([]byte, len(pl.frame)-len(pl.out)))
+		}
+		return
+	}
+
+	if pl.frames == nil {
+		return
+	}
+
+	done := pl.done
+	pl.end()
+
+	select {
+	case <-done:
+	case <-p.ctx.Done():
+	}
+}
+
+// startEncoder opens the encoder on a pipe and starts the routines that feed it and drain it to the out channel.
+func (p *Player) startEncoder() error {
+	pl := p.pipe
+	pr, pw := io.Pipe()
+
+	if err := pl.encoder.Open(pr); err != nil {
+		return err
+	}
+
+	frames := make(chan []byte)
+	done := make(chan struct{})
+	pl.frames = frames
+	pl.done = done
+
+	go func() {
+		for frame := range frames {
+			// Once the encoder stops reading, writes fail. Frames are still drained so that senders don't block.
+			_, _ = pw.Write(frame)
+		}
+		_ = pw.Close()
+	}()
+
+	go func() {
+		defer close(done)
+		// Unblock the writer if the encoder stops reading before the end.
+		defer pr.CloseWithError(io.ErrClosedPipe)
+
+		logger := p.logger.With(slog.String("goroutine", "encoder"))
+		buf := make([]byte, p.config.PacketBuffer)
+
+		for {
+			n, err := pl.encoder.Read(buf)
+			if err != nil {
+				if err != io.EOF {
+					logger.Error("error reading encoder", slog.String("error", err.Error()))
+				}
+				break
+			}
+
+			out := make([]byte, n)
+			copy(out, buf[:n])
+
+			if !send(p.ctx, p.outChan, out) {
+				break
+			}
+		}
+
+		if err := pl.encoder.Close(); err != nil {
+			logger.Error("failed closing encoder", slog.String("error", err.Error()))
+		}
+	}()
+
+	return nil
+}
+
+// stopEncoder ends the encoder session without flushing and waits for it to exit. Used when the player shuts down.
+func (p *Player) stopEncoder() {
+	pl := p.pipe
+	if pl == nil || pl.done == nil {
+		return
+	}
+
+	pl.end()
+	<-pl.done
+}
+
+// isClosed reports whether c has been closed.
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,14 +1,21 @@
 package apollo
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
+	"maps"
+	"mime"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/olympus-go/apollo/ffmpeg/probe"
+	"github.com/olympus-go/apollo/ogg"
+	"github.com/olympus-go/apollo/ogg/opus"
+	"github.com/olympus-go/apollo/ogg/vorbis"
 )
 
 type Playable interface {
@@ -22,6 +29,18 @@ type Playable interface {
 	Download() (io.ReadCloser, error)
 }
 
+// Caching is implemented by Playables that can cache their own downloads, such as spotify tracks of a session with a
+// cache. Cached reports whether they currently do, in which case the player doesn't cache them a second time.
+type Caching interface {
+	Cached() bool
+}
+
+// Identifiable is implemented by Playables that have a stable identity, such as a track ID or a file path. Together
+// with the Playable's Type, the identity is used to key cached downloads.
+type Identifiable interface {
+	Id() string
+}
+
 // LocalFile implements the Playable interface for a file local to the filesystem.
 type LocalFile struct {
 	name        string
@@ -31,20 +50,36 @@ type LocalFile struct {
 	Mdata       map[string]string
 	path        string
 	duration    time.Duration
+	loudness    Loudness
+	hasLoudness bool
+	contentType string
+	info        probe.MediaInfo
 }
 
-type ffprobeFormat struct {
-	Format struct {
-		Filename string `json:"filename"`
-		Duration string `json:"duration"`
-		Tags     struct {
-			Title  string `json:"title"`
-			Artist string `json:"artist"`
-			Album  string `json:"album"`
-		} `json:"tags"`
-	} `json:"format"`
+// audioExtensions maps common audio file extensions to their MIME types, since the system MIME tables often lack them.
+var audioExtensions = map[string]string{
+	".opus": "audio/ogg; codecs=opus",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".wav":  "audio/wav",
+	".aac":  "audio/aac",
+	".m4a":  "audio/mp4",
+	".webm": "audio/webm",
 }
 
+// extensionContentType guesses a MIME type from a file extension.
+func extensionContentType(ext string) string {
+	if contentType, ok := audioExtensions[strings.ToLower(ext)]; ok {
+		return contentType
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+// NewLocalFile creates a LocalFile from the file at path, reading its metadata with ffprobe. An error is returned if
+// the file can't be probed.
 func NewLocalFile(path string) (LocalFile, error) {
 	var err error
 	if _, err = os.Stat(path); err != nil {
@@ -57,50 +92,87 @@ func NewLocalFile(path string) (LocalFile, error) {
 		album:       "local",
 		description: "local file",
 		path:        path,
+		contentType: extensionContentType(filepath.Ext(path)),
+	}
+
+	// The comments of ogg files are read natively, and are preferred over ffprobe's.
+	if strings.HasPrefix(l.contentType, "audio/ogg") {
+		if tags, contentType, ok := readOggTags(path); ok {
+			l.contentType = contentType
+			l.applyTags(tags)
+		}
 	}
 
-	args := []string{
-		"-i",
-		path,
-		"-v",
-		"quiet",
-		"-show_format",
-		"-print_format",
-		"json=compact=1",
+	info, err := probe.File(context.Background(), path)
+	if err != nil {
+		return LocalFile{}, err
 	}
 
-	var out bytes.Buffer
-	cmd := exec.Command("ffprobe", args...)
-	cmd.Stdout = &out
-	if err = cmd.Run(); err != nil {
-		l.duration = 69 * time.Minute
-		return l, nil
+	l.info = info
+	l.duration = info.Duration
+
+	tags := info.AllTags()
+	maps.Copy(tags, l.Mdata)
+	l.applyTags(tags)
+	if contentType := info.ContentType(); contentType != "" {
+		l.contentType = contentType
 	}
 
-	var format ffprobeFormat
-	if err = json.Unmarshal(out.Bytes(), &format); err != nil {
-		l.duration = 69 * time.Minute
-		return l, nil
+	return l, nil
+}
+
+// applyTags sets the metadata of the file from tags with lowercase keys.
+func (l *LocalFile) applyTags(tags map[string]string) {
+	l.Mdata = tags
+	if tags["title"] != "" {
+		l.name = tags["title"]
+	}
+	if tags["artist"] != "" {
+		l.artist = tags["artist"]
 	}
+	if tags["album"] != "" {
+		l.album = tags["album"]
+	}
+	l.loudness, l.hasLoudness = tagLoudness(tags)
+}
 
-	if format.Format.Tags.Title != "" {
-		l.name = format.Format.Tags.Title
+// readOggTags reads the comments of the ogg vorbis or opus file at path, keyed by their lowercase names, along with
+// the file's content type. ok is false if the file isn't one.
+func readOggTags(path string) (tags map[string]string, contentType string, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", false
 	}
-	if format.Format.Tags.Artist != "" {
-		l.artist = format.Format.Tags.Artist
+	defer f.Close()
+
+	d := ogg.NewDecoder()
+	_ = d.Open(f)
+
+	head, err := d.Next()
+	if err != nil {
+		return nil, "", false
 	}
-	if format.Format.Tags.Album != "" {
-		l.album = format.Format.Tags.Album
+	packet, err := d.Next()
+	if err != nil {
+		return nil, "", false
 	}
-	if format.Format.Duration != "" {
-		l.duration, err = time.ParseDuration(fmt.Sprintf("%ss", format.Format.Duration))
+
+	switch {
+	case vorbis.IsIdentification(head):
+		comment, err := vorbis.ParseComment(packet)
+		if err != nil {
+			return nil, "", false
+		}
+		return comment.Map(), "audio/ogg; codecs=vorbis", true
+	case opus.IsHead(head):
+		opusTags, err := opus.ParseTags(packet)
 		if err != nil {
-			l.duration = 69 * time.Minute
-			return l, nil
+			return nil, "", false
 		}
+		return opusTags.Map(), "audio/ogg; codecs=opus", true
 	}
 
-	return l, nil
+	return nil, "", false
 }
 
 func (l LocalFile) Name() string {
@@ -135,6 +207,43 @@ func (l LocalFile) Download() (io.ReadCloser, error) {
 	return os.Open(l.path)
 }
 
+// Loudness returns the loudness found in the file's ReplayGain or R128 tags, if any.
+func (l LocalFile) Loudness() (Loudness, bool) {
+	return l.loudness, l.hasLoudness
+}
+
+// ContentType returns the MIME type of the file as detected by ffprobe, or guessed from its extension if ffprobe
+// couldn't tell.
+func (l LocalFile) ContentType() string {
+	return l.contentType
+}
+
+// MediaInfo returns what ffprobe reported about the file.
+func (l LocalFile) MediaInfo() probe.MediaInfo {
+	return l.info
+}
+
+// Descriptor describes the file by its absolute path.
+func (l LocalFile) Descriptor() Descriptor {
+	path, err := filepath.Abs(l.path)
+	if err != nil {
+		path = l.path
+	}
+
+	return Descriptor{Type: l.Type(), Ref: path}
+}
+
+// Id returns a hash of the file's absolute path.
+func (l LocalFile) Id() string {
+	path, err := filepath.Abs(l.path)
+	if err != nil {
+		path = l.path
+	}
+
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:])
+}
+
 // nameArtistAlbumType returns a struct that contains a playable's Name, Artist, Album, and Type.
 func nameArtistAlbumType(p Playable) any {
 	return struct {
@@ -4,10 +4,14 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"math"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/eolso/threadsafe"
+	"github.com/olympus-go/apollo/cache"
 )
 
 type PlayerState int
@@ -29,96 +33,422 @@ const (
 	PreviousState
 )
 
+type RepeatMode int
+
+func (r RepeatMode) String() string {
+	return []string{"Off", "One", "All"}[r]
+}
+
+const (
+	// RepeatOff advances through the queue once and goes idle at the end of it.
+	RepeatOff RepeatMode = iota
+	// RepeatOne replays the current Playable whenever it finishes. Skipping still moves on to the next one.
+	RepeatOne
+	// RepeatAll wraps the cursor back to the start of the queue once the end is reached.
+	RepeatAll
+)
+
 type Player struct {
-	config PlayerConfig
-	codec  Codec
+	config   PlayerConfig
+	codec    Codec
+	registry *Registry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// stateLock guards cursor, the queue pointer, currentState, repeatMode, stopAfterCurrent, playCancel and
+	// sendCancel, which the listeners change while they are read from other goroutines.
+	stateLock sync.RWMutex
 
 	cursor int
 	queue  *threadsafe.Slice[PlayableCodec]
+	cache  *cache.Cache
+
+	// queueVersion is incremented on every change to the queue. prefetched is only valid for the version it was
+	// created with.
+	queueVersion atomic.Int64
+	prefetched   atomic.Pointer[prefetch]
 
 	currentState PlayerState
 	stateChan    chan PlayerState
 
+	repeatMode       RepeatMode
+	stopAfterCurrent bool
+
 	outChan    chan []byte
-	bytesSent  int
+	sinks      *sinkSet
+	bytesSent  atomic.Int64
 	playCancel context.CancelFunc
-
+	// sendCancel cancels the send of the packet the playable listener is sending, so that a seek doesn't have to wait
+	// for the consumer of the output.
+	sendCancel context.CancelFunc
+
+	// pipe is set once an encoder is set or PCM output is enabled, in which case codec output is treated as PCM and
+	// mixed before it is encoded or sent.
+	pipe *pipeline
+	// pacer is set if PlayerConfig.Realtime is.
+	pacer *pacer
+	// volume holds the bits of the float64 master volume.
+	volume atomic.Uint64
+
+	analyzer      LoudnessAnalyzer
+	loudnessStore *loudnessStore
+	// measuring holds the keys of the Playables whose loudness is being measured in the background.
+	measuring sync.Map
+
+	seekChan chan seekRequest
+	watch    stopwatch
+
+	// mediaPosition holds the last position reported by a TimedCodec. It is only valid while mediaTimed is set.
+	mediaPosition atomic.Int64
+	mediaTimed    atomic.Bool
+
+	events *eventBus
 	logger *slog.Logger
 }
 
 type PlayableCodec struct {
 	playable Playable
 	codec    Codec
+	// gain is applied in dB on top of the master volume.
+	gain float64
+	// codecName is the name of the registered codec that codec was created by, if it was picked automatically.
+	codecName string
+}
+
+// seekRequest asks the playable listener to restart the current PlayableCodec at offset. If relative is set, offset is
+// added to the current position. The outcome is sent on result.
+type seekRequest struct {
+	offset   time.Duration
+	relative bool
+	result   chan error
+}
+
+// playRequest asks the playable listener to play pc, which is found at index in the queue.
+type playRequest struct {
+	pc    PlayableCodec
+	index int
+}
+
+// playResult is sent from the playable listener to the state listener whenever it is done with a PlayableCodec.
+type playResult struct {
+	pc      PlayableCodec
+	skipped bool
+	err     error
 }
 
 // NewPlayer creates a new player instance and starts listening for events. If no logging is desired, nil can be passed
-// in for h.
+// in for h. The player runs until Close is called.
 func NewPlayer(config PlayerConfig, h slog.Handler) *Player {
+	return NewPlayerContext(context.Background(), config, h)
+}
+
+// NewPlayerContext creates a new player instance like NewPlayer, that additionally shuts down once ctx is done.
+func NewPlayerContext(ctx context.Context, config PlayerConfig, h slog.Handler) *Player {
 	if h == nil {
 		h = nopLogHandler{}
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+
 	p := Player{
-		config:       config,
-		codec:        &NopCodec{},
-		cursor:       0,
-		queue:        &threadsafe.Slice[PlayableCodec]{},
-		currentState: IdleState,
-		stateChan:    make(chan PlayerState),
-		outChan:      make(chan []byte),
-		logger:       slog.New(h),
+		config:        config,
+		ctx:           ctx,
+		cancel:        cancel,
+		codec:         &NopCodec{},
+		cursor:        0,
+		queue:         &threadsafe.Slice[PlayableCodec]{},
+		currentState:  IdleState,
+		repeatMode:    RepeatOff,
+		stateChan:     make(chan PlayerState),
+		outChan:       make(chan []byte),
+		sinks:         newSinkSet(),
+		seekChan:      make(chan seekRequest, 1),
+		events:        newEventBus(),
+		loudnessStore: newLoudnessStore(config.LoudnessFile),
+		logger:        slog.New(h),
+	}
+
+	if config.Cache != nil {
+		p.cache = config.Cache
+	} else if config.CacheSize > 0 {
+		dir := config.CacheDir
+		if dir == "" {
+			dir = defaultCacheDir()
+		}
+
+		var err error
+		if p.cache, err = cache.New(dir, config.CacheSize); err != nil {
+			p.logger.Error("failed to open cache, caching is disabled", slog.String("error", err.Error()))
+		}
+	}
+
+	if config.Realtime {
+		p.pacer = newPacer(config.JitterBuffer)
 	}
 
+	p.volume.Store(math.Float64bits(1))
+
+	p.wg.Add(1)
 	go p.stateListener()
 
 	return &p
 }
 
+// Close stops playback and shuts the player down. Any open codec and Playable reader are closed, and the out channel is
+// closed once everything has stopped, so consumers ranging over Out terminate. Close blocks until then, but can't
+// interrupt a Playable's Download that is already in progress.
+func (p *Player) Close() error {
+	p.cancel()
+	p.wg.Wait()
+
+	return nil
+}
+
 func (p *Player) SetDefaultCodec(c Codec) {
 	if c != nil {
 		p.codec = c
 	}
 }
 
+// SetEncoder makes the player decode every queue entry to PCM and pass it through a single encoder before it is sent on
+// the out channel. This is what enables PlayerConfig.Crossfade. Codecs used for queue entries must then output PCM in
+// PlayerConfig.PCMFormat, and c is opened on that PCM whenever playback starts from idle. Passing nil goes back to
+// sending codec output directly. SetEncoder must be called before anything is played.
+func (p *Player) SetEncoder(c Codec) {
+	if c == nil {
+		p.pipe = nil
+		return
+	}
+
+	p.pipe = newPipeline(p.config.PCMFormat, p.config.FrameDuration, p.config.Crossfade, c, p.Volume())
+}
+
+// SetPCMOutput makes the player decode every queue entry to PCM like SetEncoder does, but send the PCM itself on the
+// out channel. Every packet is a frame of PlayerConfig.FrameDuration in PlayerConfig.PCMFormat, and the last frame
+// before going idle is padded with silence. SetEncoder(nil) goes back to sending codec output directly. SetPCMOutput
+// must be called before anything is played.
+func (p *Player) SetPCMOutput() {
+	p.pipe = newPipeline(p.config.PCMFormat, p.config.FrameDuration, p.config.Crossfade, nil, p.Volume())
+}
+
+// SetVolume sets the master volume as a linear factor, where 1 leaves the audio unchanged and 0 mutes it. Negative
+// values are treated as 0. The change is applied to the next audio processed, without restarting playback. Volume is
+// only applied when an encoder is set with SetEncoder or PCM output is enabled, since it needs access to the PCM.
+func (p *Player) SetVolume(volume float64) {
+	if volume < 0 || math.IsNaN(volume) {
+		volume = 0
+	}
+
+	p.volume.Store(math.Float64bits(volume))
+}
+
+func (p *Player) Volume() float64 {
+	return math.Float64frombits(p.volume.Load())
+}
+
+// Underruns returns how often audio wasn't ready by the time everything sent before it had played out, e.g. because a
+// download or codec stalled. Always 0 unless PlayerConfig.Realtime is set.
+func (p *Player) Underruns() int64 {
+	if p.pacer == nil {
+		return 0
+	}
+
+	return p.pacer.underruns.Load()
+}
+
+// Overruns returns how often the out channel's consumer took longer than PlayerConfig.JitterBuffer to accept audio that
+// was due, meaning it didn't keep up with play speed. Always 0 unless PlayerConfig.Realtime is set.
+func (p *Player) Overruns() int64 {
+	if p.pacer == nil {
+		return 0
+	}
+
+	return p.pacer.overruns.Load()
+}
+
+// SetRegistry makes the player pick codecs automatically. Playables enqueued without a codec that advertise a content
+// type, by implementing ContentTyped, get a new codec from reg for that content type. All other Playables still use the
+// default codec. The registry is also used by Snapshot and Restore if none is passed to them.
+func (p *Player) SetRegistry(reg *Registry) {
+	p.registry = reg
+}
+
 func (p *Player) Play() {
-	go func() {
-		p.stateChan <- PlayState
-	}()
+	p.requestState(PlayState)
 }
 
 func (p *Player) Pause() {
-	go func() {
-		p.stateChan <- PauseState
-	}()
+	p.requestState(PauseState)
 }
 
+// Enqueue adds playable to the end of the queue, with a codec picked by the registry if one is set, or the default
+// codec otherwise.
 func (p *Player) Enqueue(playable Playable) {
-	p.EnqueueWithCodec(playable, p.codec)
+	p.EnqueueWithGain(playable, nil, 0)
 }
 
 func (p *Player) EnqueueWithCodec(playable Playable, codec Codec) {
+	p.EnqueueWithGain(playable, codec, 0)
+}
+
+// EnqueueWithGain enqueues playable like EnqueueWithCodec, additionally adjusting its level by gain dB relative to the
+// master volume. Like SetVolume, gain is only applied to PCM, when an encoder is set or PCM output is enabled.
+func (p *Player) EnqueueWithGain(playable Playable, codec Codec, gain float64) {
 	if playable == nil {
 		return
 	}
 
-	if codec == nil {
-		codec = p.codec
+	p.queue.Append(p.newPlayableCodec(playable, codec, gain))
+	p.invalidatePrefetch()
+	p.logger.Info("enqueued "+playable.Type(), slog.Any("playable", nameArtistAlbumType(playable)))
+	p.emit(PlayerEvent{Type: QueueChanged, Playable: playable})
+}
+
+// newPlayableCodec creates a queue entry for playable. If codec is nil, the registry picks one for playable, falling
+// back to the default codec.
+func (p *Player) newPlayableCodec(playable Playable, codec Codec, gain float64) PlayableCodec {
+	pc := PlayableCodec{playable: playable, codec: codec, gain: gain}
+	if codec != nil {
+		return pc
+	}
+
+	if p.registry != nil {
+		if pc.codec, pc.codecName, _ = p.registry.CodecFor(playable); pc.codec != nil {
+			return pc
+		}
 	}
 
-	p.queue.Append(PlayableCodec{playable: playable, codec: codec})
-	p.logger.Info("enqueued "+playable.Type(), slog.Any("playable", nameArtistAlbumType(playable)))
+	pc.codec = p.codec
+
+	return pc
+}
+
+// Seek restarts the currently playing Playable at offset. The Playable is downloaded again and its codec has to
+// implement SeekableCodec, otherwise ErrSeekUnsupported is returned. ErrNotPlaying is returned if nothing is playing.
+//
+// Seek blocks until the Playable has been restarted, but never on the consumer of the output: a packet still waiting
+// to be read from the out channel is dropped. Seek is therefore safe to call from the goroutine reading Out.
+func (p *Player) Seek(offset time.Duration) error {
+	return p.seek(seekRequest{offset: offset})
+}
+
+// SeekBy moves the playback position of the currently playing Playable by d, which may be negative. See Seek.
+func (p *Player) SeekBy(d time.Duration) error {
+	return p.seek(seekRequest{offset: d, relative: true})
+}
+
+func (p *Player) seek(req seekRequest) error {
+	if state := p.State(); state != PlayState && state != PauseState {
+		return ErrNotPlaying
+	}
+
+	req.result = make(chan error, 1)
+	if !send(p.ctx, p.seekChan, req) {
+		return ErrPlayerClosed
+	}
+	p.interruptSend()
+
+	select {
+	case err := <-req.result:
+		return err
+	case <-p.ctx.Done():
+		return ErrPlayerClosed
+	}
+}
+
+// interruptSend cancels the send of the packet the playable listener is sending, if any.
+func (p *Player) interruptSend() {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.sendCancel != nil {
+		p.sendCancel()
+	}
+}
+
+// sendContext returns the context the playable listener sends its next packet with, which is cancelled by
+// interruptSend.
+func (p *Player) sendContext(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.sendCancel != nil {
+		p.sendCancel()
+	}
+	p.sendCancel = cancel
+
+	return ctx
+}
+
+// playContext returns a new context for the playable listener to play with, which is cancelled by cancelPlay.
+func (p *Player) playContext() context.Context {
+	ctx, cancel := context.WithCancel(p.ctx)
+
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.playCancel != nil {
+		p.playCancel()
+	}
+	p.playCancel = cancel
+
+	return ctx
+}
+
+// cancelPlay skips whatever the playable listener is playing.
+func (p *Player) cancelPlay() {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.playCancel != nil {
+		p.playCancel()
+	}
 }
 
 func (p *Player) Next() {
-	go func() {
-		p.stateChan <- NextState
-	}()
+	p.requestState(NextState)
 }
 
 func (p *Player) Previous() {
-	go func() {
-		p.stateChan <- PreviousState
-	}()
+	p.requestState(PreviousState)
+}
+
+// SetRepeatMode changes how the cursor advances once a Playable finishes.
+func (p *Player) SetRepeatMode(mode RepeatMode) {
+	p.stateLock.Lock()
+	p.repeatMode = mode
+	p.stateLock.Unlock()
+
+	p.invalidatePrefetch()
+}
+
+func (p *Player) RepeatMode() RepeatMode {
+	p.stateLock.RLock()
+	defer p.stateLock.RUnlock()
+
+	return p.repeatMode
+}
+
+// SetStopAfterCurrent makes the player go idle once the current Playable finishes instead of advancing the queue. It
+// only applies once; calling Play afterward continues with the next entry as usual. Skipping doesn't count as
+// finishing.
+func (p *Player) SetStopAfterCurrent(stop bool) {
+	p.stateLock.Lock()
+	p.stopAfterCurrent = stop
+	p.stateLock.Unlock()
+
+	p.invalidatePrefetch()
+}
+
+func (p *Player) StopAfterCurrent() bool {
+	p.stateLock.RLock()
+	defer p.stateLock.RUnlock()
+
+	return p.stopAfterCurrent
 }
 
 // Get returns the Playable at position i. Returns nil when i is invalid.
@@ -127,20 +457,31 @@ func (p *Player) Get(i int) Playable {
 	return pc.playable
 }
 
+// Insert adds playable to the queue at position i. The codec is picked like for Enqueue.
 func (p *Player) Insert(i int, playable Playable) {
-	p.InsertWithCodec(i, playable, p.codec)
+	p.InsertWithGain(i, playable, nil, 0)
 }
 
 func (p *Player) InsertWithCodec(i int, playable Playable, codec Codec) {
+	p.InsertWithGain(i, playable, codec, 0)
+}
+
+// InsertWithGain inserts playable like InsertWithCodec, additionally adjusting its level by gain dB. See
+// EnqueueWithGain.
+func (p *Player) InsertWithGain(i int, playable Playable, codec Codec, gain float64) {
 	if i < 0 {
 		return
 	}
 
+	pc := p.newPlayableCodec(playable, codec, gain)
 	if i >= p.queue.Len() {
-		p.queue.Append(PlayableCodec{playable: playable, codec: codec})
+		p.queue.Append(pc)
 	} else {
-		p.queue.SafeInsert(i, PlayableCodec{playable: playable, codec: codec})
+		p.queue.SafeInsert(i, pc)
 	}
+	p.invalidatePrefetch()
+
+	p.emit(PlayerEvent{Type: QueueChanged, Playable: playable})
 }
 
 func (p *Player) Remove(i int) {
@@ -148,7 +489,10 @@ func (p *Player) Remove(i int) {
 		return
 	}
 
+	pc, _ := p.queue.SafeGet(i)
 	p.queue.SafeDelete(i)
+	p.invalidatePrefetch()
+	p.emit(PlayerEvent{Type: QueueChanged, Playable: pc.playable})
 }
 
 func (p *Player) List(all bool) []Playable {
@@ -163,18 +507,18 @@ func (p *Player) List(all bool) []Playable {
 		return playables
 	}
 
-	return playables[p.cursor:]
+	return playables[p.Cursor():]
 }
 
 func (p *Player) Empty() {
 	p.queue.Empty()
+	p.stateLock.Lock()
 	p.cursor = 0
-
-	if p.playCancel != nil {
-		p.playCancel()
-	}
-
-	p.bytesSent = 0
+	p.stateLock.Unlock()
+	p.invalidatePrefetch()
+	p.cancelPlay()
+	p.bytesSent.Store(0)
+	p.emit(PlayerEvent{Type: QueueChanged})
 }
 
 func (p *Player) Shuffle(all bool) {
@@ -182,7 +526,7 @@ func (p *Player) Shuffle(all bool) {
 	end := p.queue.Len()
 
 	if !all {
-		start = p.cursor
+		start = p.Cursor()
 	}
 
 	shuffledQueue := threadsafe.Slice[PlayableCodec]{}
@@ -210,12 +554,22 @@ func (p *Player) Shuffle(all bool) {
 		newQueue.Append(shuffledQueue.Get(i - start))
 	}
 
+	p.stateLock.Lock()
 	p.queue = &newQueue
+	p.stateLock.Unlock()
+
+	p.invalidatePrefetch()
+	p.emit(PlayerEvent{Type: QueueChanged})
 }
 
 func (p *Player) NowPlaying() (Playable, bool) {
-	if p.currentState == PlayState || p.currentState == PauseState {
-		pc, ok := p.queue.SafeGet(p.cursor - 1)
+	// The state and cursor are read together, since the cursor only points past the current Playable while playing.
+	p.stateLock.RLock()
+	state, cursor := p.currentState, p.cursor
+	p.stateLock.RUnlock()
+
+	if state == PlayState || state == PauseState {
+		pc, ok := p.queue.SafeGet(cursor - 1)
 
 		return pc.playable, ok
 	}
@@ -224,59 +578,149 @@ func (p *Player) NowPlaying() (Playable, bool) {
 }
 
 func (p *Player) Cursor() int {
+	p.stateLock.RLock()
+	defer p.stateLock.RUnlock()
+
 	return p.cursor
 }
 
 func (p *Player) State() PlayerState {
+	p.stateLock.RLock()
+	defer p.stateLock.RUnlock()
+
 	return p.currentState
 }
 
+// Out returns the channel the player's output is sent on. It is closed once the player shuts down. Only a single
+// consumer can read it; use AddSink to feed several.
 func (p *Player) Out() <-chan []byte {
 	return p.outChan
 }
 
+// BytesSent returns the number of packets sent on the out channel for the current Playable. When an encoder is set or
+// PCM output is enabled, the number of PCM frames read from the Playable is returned instead. Position should be used
+// for any time based progress.
 func (p *Player) BytesSent() int {
-	// TODO the player should probably track time itself, but this will be a quicker win.
-	return p.bytesSent
+	return int(p.bytesSent.Load())
+}
+
+// Position returns the media time reached in the current Playable. If the codec implements TimedCodec, the position it
+// reports is used. Otherwise, the position is estimated from the time spent playing since the Playable started or was
+// last seeked. Time spent paused is never counted. Returns 0 if nothing is playing.
+func (p *Player) Position() time.Duration {
+	if _, ok := p.NowPlaying(); !ok {
+		return 0
+	}
+
+	if p.mediaTimed.Load() {
+		return time.Duration(p.mediaPosition.Load())
+	}
+
+	return p.watch.Elapsed()
+}
+
+// Remaining returns the time left in the current Playable based on its Duration and Position. Returns 0 if nothing is
+// playing.
+func (p *Player) Remaining() time.Duration {
+	playable, ok := p.NowPlaying()
+	if !ok {
+		return 0
+	}
+
+	remaining := playable.Duration() - p.Position()
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// resetPosition restarts position tracking at offset for a freshly opened codec.
+func (p *Player) resetPosition(offset time.Duration) {
+	p.watch.Reset(offset)
+	p.mediaTimed.Store(false)
+	p.mediaPosition.Store(int64(offset))
+
+	if p.pipe != nil {
+		p.pipe.restart(offset)
+	}
+}
+
+// updatePosition stores the position reported by codec, if it is a TimedCodec that knows its position.
+func (p *Player) updatePosition(codec Codec) {
+	if timed, ok := codec.(TimedCodec); ok {
+		if position, ok := timed.Position(); ok {
+			p.mediaPosition.Store(int64(position))
+			p.mediaTimed.Store(true)
+		}
+	}
+}
+
+// resetPacer restarts the pacing clock, if pacing is enabled, after a gap in the audio.
+func (p *Player) resetPacer() {
+	if p.pacer != nil {
+		p.pacer.reset()
+	}
+}
+
+// codecGain returns the gain in dB that codec asks for, if it is a GainCodec.
+func codecGain(codec Codec) float64 {
+	if c, ok := codec.(GainCodec); ok {
+		return c.OutputGain()
+	}
+
+	return 0
+}
+
+// checkMetadata emits a MetadataChanged event if pc's codec is a MetadataCodec that reports new metadata.
+func (p *Player) checkMetadata(pc PlayableCodec) {
+	if codec, ok := pc.codec.(MetadataCodec); ok && codec.MetadataChanged() {
+		p.emit(PlayerEvent{Type: MetadataChanged, Playable: pc.playable})
+	}
 }
 
 // stateListener handles all the state change requests. This routine also launches the playable listener and establishes
 // a channel to communicate with it.
 func (p *Player) stateListener() {
+	defer p.wg.Done()
+
 	logger := p.logger.With(slog.String("goroutine", "stateListener()"))
-	processChan, playChan := p.playableListener()
+	processChan, playChan, doneChan := p.playableListener()
 
 	for {
 		select {
+		case <-p.ctx.Done():
+			logger.Debug("player closed")
+			return
 		case state := <-p.stateChan:
 			logger.Debug("received request for state change",
-				slog.String("current", p.currentState.String()),
+				slog.String("current", p.State().String()),
 				slog.String("requested", state.String()),
 			)
 
+			current := p.State()
+
 			switch state {
 			case IdleState:
-				p.currentState = IdleState
+				p.setState(IdleState)
 			case PlayState:
-				if p.currentState == IdleState {
-					if playable, ok := p.queue.SafeGet(p.cursor); ok {
-						playChan <- playable
-						p.moveCursor(1)
-						p.currentState = PlayState
+				if current == IdleState {
+					p.playNext(playChan)
+				} else if current == PauseState {
+					if send(p.ctx, processChan, PlayState) {
+						p.setState(PlayState)
 					}
-				} else if p.currentState == PauseState {
-					processChan <- PlayState
-					p.currentState = PlayState
 				}
 			case PauseState:
-				if p.currentState == PlayState {
-					processChan <- PauseState
-					p.currentState = PauseState
+				if current == PlayState {
+					if send(p.ctx, processChan, PauseState) {
+						p.setState(PauseState)
+					}
 				}
 			case NextState:
-				if p.currentState == PlayState || p.currentState == PauseState {
-					p.currentState = NextState
-					processChan <- NextState
+				if current == PlayState || current == PauseState {
+					p.setState(NextState)
+					send(p.ctx, processChan, NextState)
 				}
 			case PreviousState:
 				if p.queue.Len() > 0 {
@@ -285,116 +729,317 @@ func (p *Player) stateListener() {
 
 					// When at the end we only need to go back one (idle -> previous song). If we are currently playing
 					// or paused we need to go back two (playing -> beginning of song -> previous song).
-					switch p.currentState {
+					switch current {
 					case PlayState, PauseState:
 						p.moveCursor(-2)
-						processChan <- NextState
+						send(p.ctx, processChan, NextState)
 					case IdleState:
 						p.moveCursor(-1)
 						p.Play()
 					}
 				}
 			}
+		case result := <-doneChan:
+			// The player only goes idle if nothing follows, so that moving on to the next Playable doesn't show up as
+			// a change of state.
+			if !result.skipped && p.takeStopAfterCurrent() {
+				logger.Debug("stopping after current")
+				p.setState(IdleState)
+				send(p.ctx, processChan, IdleState)
+				continue
+			}
+
+			// The cursor already points past the Playable that just ended. Repeating it means stepping back, unless it
+			// was skipped or failed, in which case repeating would only get stuck on it.
+			if p.RepeatMode() == RepeatOne && !result.skipped && result.err == nil {
+				p.moveCursor(-1)
+			}
+
+			p.stateLock.Lock()
+			if p.repeatMode == RepeatAll && p.cursor >= p.queue.Len() {
+				p.cursor = 0
+			}
+			p.stateLock.Unlock()
+
+			// Attempt to play the next in queue
+			if !p.playNext(playChan) {
+				p.setState(IdleState)
+				send(p.ctx, processChan, IdleState)
+				p.emit(PlayerEvent{Type: QueueExhausted})
+			}
 		}
 	}
 }
 
-func (p *Player) playableListener() (chan<- PlayerState, chan<- PlayableCodec) {
+// takeStopAfterCurrent reports whether the player should stop after the current Playable, and resets it since it only
+// applies once.
+func (p *Player) takeStopAfterCurrent() bool {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	stop := p.stopAfterCurrent
+	p.stopAfterCurrent = false
+
+	return stop
+}
+
+// playNext sends the PlayableCodec at the cursor to the playable listener and advances the cursor. Returns false if
+// there was nothing left to play.
+func (p *Player) playNext(playChan chan<- playRequest) bool {
+	index := p.Cursor()
+	pc, ok := p.queue.SafeGet(index)
+	if !ok {
+		return false
+	}
+
+	p.moveCursor(1)
+	p.setState(PlayState)
+
+	return send(p.ctx, playChan, playRequest{pc: pc, index: index})
+}
+
+// setState updates the current state and emits a StateChanged event if it differs from the previous one.
+func (p *Player) setState(state PlayerState) {
+	p.stateLock.Lock()
+	previous := p.currentState
+	p.currentState = state
+	p.stateLock.Unlock()
+
+	if previous == state {
+		return
+	}
+
+	p.emit(PlayerEvent{Type: StateChanged, State: state, PreviousState: previous})
+}
+
+// playableListener launches the routine responsible for downloading, decoding and sending playables to the out
+// channel. Once it is done with a PlayableCodec, for whatever reason, the outcome is sent on the returned done channel.
+// The routine owns the out channel and closes it when the player shuts down.
+func (p *Player) playableListener() (chan<- PlayerState, chan<- playRequest, <-chan playResult) {
 	logger := p.logger.With(slog.String("goroutine", "playableListener()"))
 	stateChan := make(chan PlayerState)
-	playChan := make(chan PlayableCodec)
+	playChan := make(chan playRequest)
+	// The done channel is buffered so that reporting a result never blocks on the state listener, which may itself be
+	// waiting to send on stateChan.
+	doneChan := make(chan playResult, 1)
 
+	p.wg.Add(1)
 	go func() {
+		defer p.wg.Done()
+		defer close(p.outChan)
+		defer p.stopEncoder()
+		defer p.invalidatePrefetch()
+
 		var playerCtx context.Context
 		buf := make([]byte, p.config.PacketBuffer)
 
 		for {
-			playerCtx, p.playCancel = context.WithCancel(context.Background())
+			playerCtx = p.playContext()
 
 			select {
+			case <-p.ctx.Done():
+				return
 			case s := <-stateChan:
+				// Going idle means nothing follows, so whatever the pipeline still holds can be sent out.
+				if s == IdleState {
+					if p.pipe != nil {
+						p.flushPipeline()
+					}
+					p.resetPacer()
+					continue
+				}
+
 				// Nothing to do if not currently playing, but we don't want to have the channel backed up when idle
 				logger.Debug("discarded state change request", slog.String("requested", s.String()))
-			case pc := <-playChan:
-				p.bytesSent = 0
+			case req := <-p.seekChan:
+				req.result <- ErrNotPlaying
+			case req := <-playChan:
+				p.bytesSent.Store(0)
+				pc := req.pc
 				playable := pc.playable
 
-				logger.Info("downloading "+playable.Type(), slog.Any("playable", nameArtistAlbumType(playable)))
-
-				r, err := playable.Download()
+				r, err := p.open(req)
 				if err != nil {
-					logger.Error("failed to download as "+playable.Type(),
-						slog.String("error", err.Error()),
-						slog.Any("playable", nameArtistAlbumType(playable)),
-					)
+					p.emit(PlayerEvent{Type: TrackFailed, Playable: playable, Err: err})
+					send(p.ctx, doneChan, playResult{pc: pc, err: err})
 					continue
 				}
 
-				err = pc.codec.Open(r)
-				if err != nil {
-					logger.Error("failed to open as "+playable.Type(),
-						slog.String("error", err.Error()),
-						slog.Any("playable", nameArtistAlbumType(playable)),
-					)
-					continue
+				pc.gain += p.normalizationGain(playable)
+
+				p.resetPosition(0)
+				p.emit(PlayerEvent{Type: TrackStarted, Playable: playable})
+
+				// handleSeek restarts the codec at the requested offset. If the codec was already torn down when the
+				// seek failed, the track can't continue and false is returned.
+				var seekErr error
+				handleSeek := func(req seekRequest) bool {
+					offset := req.offset
+					if req.relative {
+						offset += p.Position()
+					}
+					if offset < 0 {
+						offset = 0
+					}
+
+					var newR io.ReadCloser
+					newR, seekErr = p.reopen(pc, r, offset)
+					req.result <- seekErr
+
+					if seekErr == ErrSeekUnsupported {
+						seekErr = nil
+						return true
+					}
+
+					r = newR
+					if seekErr != nil {
+						logger.Error("failed to seek "+playable.Type(),
+							slog.String("error", seekErr.Error()),
+							slog.Any("playable", nameArtistAlbumType(playable)),
+						)
+						return false
+					}
+
+					p.resetPosition(offset)
+					p.resetPacer()
+					if p.pipe != nil {
+						p.pipe.fader.Reset()
+					}
+					return true
 				}
 
-				func() {
+				result := func() playResult {
 					for {
 						select {
 						case <-playerCtx.Done():
 							logger.Debug("player context closed 1")
-							return
+							return playResult{pc: pc, skipped: true}
+						case req := <-p.seekChan:
+							if !handleSeek(req) {
+								return playResult{pc: pc, err: seekErr}
+							}
 						case state := <-stateChan:
 							switch state {
 							case PauseState:
-								func() {
+								p.watch.Pause()
+								ok := func() bool {
 									// Start blocking until we receive a Play or Skip state request
-									for s := range stateChan {
-										if s == PlayState {
-											return
-										} else if s == NextState {
-											logger.Debug("skipping "+playable.Type(),
-												slog.Any("playable", nameArtistAlbumType(playable)),
-											)
-											// In the case of a PlayState being received, we just need to stop blocking
-											// here. But when a NextState is received, we need to stop blocking and
-											// signal parent loop to cancel.
-											p.playCancel()
+									for {
+										select {
+										case <-playerCtx.Done():
+											return true
+										case req := <-p.seekChan:
+											if !handleSeek(req) {
+												return false
+											}
+											p.watch.Pause()
+										case s := <-stateChan:
+											if s == PlayState {
+												return true
+											} else if s == NextState {
+												logger.Debug("skipping "+playable.Type(),
+													slog.Any("playable", nameArtistAlbumType(playable)),
+												)
+												// In the case of a PlayState being received, we just need to stop
+												// blocking here. But when a NextState is received, we need to stop
+												// blocking and signal parent loop to cancel.
+												p.cancelPlay()
+												return true
+											}
 										}
 									}
 								}()
+								if !ok {
+									return playResult{pc: pc, err: seekErr}
+								}
+								p.resetPacer()
+								p.watch.Resume()
 							case NextState:
 								logger.Debug("skipping "+playable.Type(),
 									slog.Any("playable", nameArtistAlbumType(playable)),
 								)
-								p.playCancel()
+								p.cancelPlay()
 							}
 						default:
+							// The send is interrupted by a seek, which is handled once back in the select.
+							sendCtx := p.sendContext(playerCtx)
+
+							if p.pipe != nil {
+								switch err := p.process(sendCtx, pc); err {
+								case nil:
+									continue
+								case io.EOF:
+									logger.Info("finished playing "+playable.Type(),
+										slog.Any("playable", nameArtistAlbumType(playable)),
+									)
+									return playResult{pc: pc}
+								case context.Canceled:
+									if playerCtx.Err() == nil {
+										continue
+									}
+									logger.Debug("player context closed 2")
+									return playResult{pc: pc, skipped: true}
+								default:
+									logger.Error("error reading "+playable.Type(),
+										slog.String("error", err.Error()),
+										slog.Any("playable", nameArtistAlbumType(playable)),
+									)
+									return playResult{pc: pc, err: err}
+								}
+							}
+
+							previous := time.Duration(p.mediaPosition.Load())
 							n, err := pc.codec.Read(buf)
 							if err != nil && err == io.EOF {
 								logger.Info("finished playing "+playable.Type(),
 									slog.Any("playable", nameArtistAlbumType(playable)),
 								)
-								return
+								return playResult{pc: pc}
 							} else if err != nil {
-								slog.Error("error reading "+playable.Type(),
+								logger.Error("error reading "+playable.Type(),
 									slog.String("error", err.Error()),
 									slog.Any("playable", nameArtistAlbumType(playable)),
 								)
-								return
+								return playResult{pc: pc, err: err}
+							}
+
+							p.updatePosition(pc.codec)
+							p.checkMetadata(pc)
+							p.maybePrefetch(pc)
+
+							// Without a known position, the packet's length is unknown and pace sends it right away.
+							var duration time.Duration
+							if p.mediaTimed.Load() {
+								duration = time.Duration(p.mediaPosition.Load()) - previous
+							}
+
+							if p.config.OpusPackets {
+								packetDuration, header, err := opusPacket(buf[:n])
+								if header {
+									continue
+								}
+								if err != nil {
+									logger.Warn("dropping output of "+playable.Type()+" that isn't an opus packet",
+										slog.String("error", err.Error()),
+										slog.Any("playable", nameArtistAlbumType(playable)),
+									)
+									continue
+								}
+								// Pages hold many packets, so the packet's own length paces more evenly.
+								duration = packetDuration
 							}
 
 							out := make([]byte, n)
 							copy(out, buf[:n])
 
-							select {
-							case <-playerCtx.Done():
+							if !pace(sendCtx, p.pacer, p.outChan, out, duration) {
+								if playerCtx.Err() == nil {
+									continue
+								}
 								logger.Debug("player context closed 2")
-								return
-							case p.outChan <- out:
-								p.bytesSent++
+								return playResult{pc: pc, skipped: true}
 							}
+							p.bytesSent.Add(1)
 						}
 					}
 				}()
@@ -403,26 +1048,123 @@ func (p *Player) playableListener() (chan<- PlayerState, chan<- PlayableCodec) {
 					logger.Error("failed closing codec", slog.String("error", err.Error()))
 				}
 
-				if err = r.Close(); err != nil {
-					logger.Error("failed closing "+playable.Type(),
-						slog.String("error", err.Error()),
-						slog.Any("playable", nameArtistAlbumType(playable)),
-					)
+				// r is nil when a failed seek already closed the original reader.
+				if r != nil {
+					if err = r.Close(); err != nil {
+						logger.Error("failed closing "+playable.Type(),
+							slog.String("error", err.Error()),
+							slog.Any("playable", nameArtistAlbumType(playable)),
+						)
+					}
+				}
+
+				if p.pipe != nil {
+					// Only a Playable that played to the end is faded into the next one.
+					if result.err == nil && !result.skipped {
+						p.pipe.fader.End()
+					} else {
+						p.pipe.fader.Reset()
+					}
 				}
 
-				// Attempt to play the next in queue
-				p.stateChan <- IdleState
-				p.stateChan <- PlayState
+				switch {
+				case result.err != nil:
+					p.emit(PlayerEvent{Type: TrackFailed, Playable: playable, Err: result.err})
+				case result.skipped:
+					p.emit(PlayerEvent{Type: TrackSkipped, Playable: playable})
+				default:
+					p.emit(PlayerEvent{Type: TrackFinished, Playable: playable})
+				}
+
+				send(p.ctx, doneChan, result)
 			}
 		}
 	}()
 
-	return stateChan, playChan
+	return stateChan, playChan, doneChan
+}
+
+// open downloads the Playable of req and opens its codec on it, unless a prefetch has already done either.
+func (p *Player) open(req playRequest) (io.ReadCloser, error) {
+	logger := p.logger.With(slog.Any("playable", nameArtistAlbumType(req.pc.playable)))
+	playable := req.pc.playable
+
+	var r io.ReadCloser
+	var opened bool
+	var err error
+
+	if pf := p.claimPrefetch(req.index); pf != nil {
+		var ok bool
+		if r, opened, ok, err = pf.claim(p.ctx); ok {
+			logger.Debug("using prefetched " + playable.Type())
+		}
+	}
+
+	if r == nil && err == nil {
+		logger.Info("downloading " + playable.Type())
+		r, err = p.download(playable)
+	}
+
+	if err != nil {
+		logger.Error("failed to download as "+playable.Type(), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if !opened {
+		if err = req.pc.codec.Open(r); err != nil {
+			logger.Error("failed to open as "+playable.Type(), slog.String("error", err.Error()))
+			_ = r.Close()
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// download returns a reader for playable, going through the cache if it is enabled and the playable is Identifiable.
+// Playables that cache their downloads themselves aren't cached a second time.
+func (p *Player) download(playable Playable) (io.ReadCloser, error) {
+	identifiable, ok := playable.(Identifiable)
+	if p.cache == nil || !ok {
+		return playable.Download()
+	}
+	if caching, ok := playable.(Caching); ok && caching.Cached() {
+		return playable.Download()
+	}
+
+	return p.cache.Open(playable.Type()+":"+identifiable.Id(), playable.Download)
+}
+
+// reopen closes the codec and reader of pc and opens them again from a fresh download, starting at offset. The new
+// reader is returned; it is nil if the download failed. If the codec can't seek, nothing is closed and r is returned
+// along with ErrSeekUnsupported.
+func (p *Player) reopen(pc PlayableCodec, r io.ReadCloser, offset time.Duration) (io.ReadCloser, error) {
+	codec, ok := pc.codec.(SeekableCodec)
+	if !ok {
+		return r, ErrSeekUnsupported
+	}
+
+	if err := codec.Close(); err != nil {
+		p.logger.Error("failed closing codec", slog.String("error", err.Error()))
+	}
+	if err := r.Close(); err != nil {
+		p.logger.Error("failed closing "+pc.playable.Type(), slog.String("error", err.Error()))
+	}
+
+	newR, err := p.download(pc.playable)
+	if err != nil {
+		return nil, err
+	}
+
+	return newR, codec.OpenAt(newR, offset)
 }
 
 // moveCursor moves the cursor the by the specified amount and then checks that it is still in the accepted bounds
 // [0, len(queue)]. If it is out of bounds, it sets the cursor to the nearest acceptable value.
 func (p *Player) moveCursor(i int) {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
 	tempCursor := p.cursor
 	tempCursor += i
 	if tempCursor < 0 {
@@ -435,7 +1177,22 @@ func (p *Player) moveCursor(i int) {
 }
 
 func (p *Player) idle() {
+	p.requestState(IdleState)
+}
+
+// requestState asynchronously sends a state change request to the state listener.
+func (p *Player) requestState(state PlayerState) {
 	go func() {
-		p.stateChan <- IdleState
+		send(p.ctx, p.stateChan, state)
 	}()
 }
+
+// send sends v on ch unless ctx is done first. Returns false if v wasn't sent.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- v:
+		return true
+	}
+}
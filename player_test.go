@@ -0,0 +1,507 @@
+package apollo_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/ogg"
+)
+
+func TestPlayer_OpusPackets(t *testing.T) {
+	type test struct {
+		opusPackets bool
+		expected    [][]byte
+	}
+
+	head := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+	tags := []byte("OpusTags")
+	audio := [][]byte{{31 << 3, 1}, {31<<3 | 3, 0}, {31 << 3, 2}}
+
+	tests := map[string]test{
+		"enabled":  {opusPackets: true, expected: [][]byte{audio[0], audio[2]}},
+		"disabled": {opusPackets: false, expected: append([][]byte{head, tags}, audio...)},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := ogg.NewEncoder(&buf, 1)
+			for i, packet := range append([][]byte{head, tags}, audio...) {
+				_ = e.WritePacket(packet, int64(max(i-1, 0)*960))
+			}
+			_ = e.Close()
+
+			p := apollo.NewPlayer(apollo.PlayerConfig{OpusPackets: tst.opusPackets, PacketBuffer: 1024}, nil)
+			defer p.Close()
+
+			p.EnqueueWithCodec(testPlayable{data: buf.Bytes()}, ogg.NewDecoder())
+			p.Play()
+
+			for i, expected := range tst.expected {
+				select {
+				case packet := <-p.Out():
+					if !bytes.Equal(packet, expected) {
+						t.Fatalf("expected packet %d to be %v; got %v", i, expected, packet)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("expected packet %d; got none", i)
+				}
+			}
+
+			select {
+			case packet := <-p.Out():
+				t.Fatalf("expected no more packets; got %v", packet)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestPlayer_Seek(t *testing.T) {
+	type test struct {
+		codec apollo.Codec
+		seek  func(p *apollo.Player) error
+		err   error
+		// first is the index of the first packet expected after seeking, or -1 if none is.
+		first int
+	}
+
+	tests := map[string]test{
+		"seek": {
+			codec: &seekCodec{},
+			seek:  func(p *apollo.Player) error { return p.Seek(time.Second) },
+			err:   nil,
+			first: 50,
+		},
+		"unsupported": {
+			codec: &apollo.NopCodec{},
+			seek:  func(p *apollo.Player) error { return p.Seek(time.Second) },
+			err:   apollo.ErrSeekUnsupported,
+			first: 2,
+		},
+		"negative_seek_by": {
+			codec: &seekCodec{},
+			seek:  func(p *apollo.Player) error { return p.SeekBy(-time.Hour) },
+			err:   nil,
+			first: 0,
+		},
+		"past_end": {
+			codec: &seekCodec{},
+			seek:  func(p *apollo.Player) error { return p.Seek(time.Hour) },
+			err:   nil,
+			first: -1,
+		},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 1}, nil)
+			defer p.Close()
+
+			p.EnqueueWithCodec(testPlayable{data: packets(100)}, tst.codec)
+			p.Play()
+
+			// Seeking from the goroutine reading Out must not wait for the packet being sent to be read.
+			if _, ok := nextPacket(p); !ok {
+				t.Fatal("expected a packet; got none")
+			}
+
+			done := make(chan error, 1)
+			go func() { done <- tst.seek(p) }()
+
+			select {
+			case err := <-done:
+				if err != tst.err {
+					t.Fatalf("expected error %v; got %v", tst.err, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected seek to return; it is blocked")
+			}
+
+			index, ok := nextPacket(p)
+			if tst.first < 0 {
+				if ok {
+					t.Fatalf("expected no packets after seeking; got packet %d", index)
+				}
+				return
+			}
+
+			// The first packet after the seek may be dropped along with the one that was waiting to be sent.
+			if !ok || index < tst.first || index > tst.first+1 {
+				t.Fatalf("expected packet %d after seeking; got %d", tst.first, index)
+			}
+		})
+	}
+}
+
+func TestPlayer_SeekOgg(t *testing.T) {
+	p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 1024}, nil)
+	defer p.Close()
+
+	p.EnqueueWithCodec(testPlayable{data: opusStream(100)}, ogg.NewDecoder())
+	p.Play()
+
+	if _, ok := nextAudio(p); !ok {
+		t.Fatal("expected audio; got none")
+	}
+	if err := p.Seek(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// The page holding the offset is the first one kept, give or take the packet that was waiting to be sent.
+	if index, ok := nextAudio(p); !ok || index < 49 || index > 51 {
+		t.Fatalf("expected packet 50 after seeking; got %d", index)
+	}
+}
+
+func TestPlayer_SeekIdle(t *testing.T) {
+	p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 1}, nil)
+	defer p.Close()
+
+	if err := p.Seek(time.Second); err != apollo.ErrNotPlaying {
+		t.Fatalf("expected error %v; got %v", apollo.ErrNotPlaying, err)
+	}
+}
+
+func TestPlayer_Position(t *testing.T) {
+	type test struct {
+		duration  time.Duration
+		play      bool
+		seek      time.Duration
+		end       bool
+		position  time.Duration
+		remaining time.Duration
+	}
+
+	tests := map[string]test{
+		"before_play": {duration: 2 * time.Second, play: false, position: 0, remaining: 0},
+		"after_seek": {
+			duration:  2 * time.Second,
+			play:      true,
+			seek:      time.Second,
+			position:  time.Second,
+			remaining: time.Second,
+		},
+		"past_duration": {
+			duration:  time.Second,
+			play:      true,
+			seek:      1500 * time.Millisecond,
+			position:  1500 * time.Millisecond,
+			remaining: 0,
+		},
+		"end": {duration: 2 * time.Second, play: true, end: true, position: 0, remaining: 0},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 1}, nil)
+			defer p.Close()
+
+			p.EnqueueWithCodec(testPlayable{data: packets(100), duration: tst.duration}, &seekCodec{})
+
+			if tst.play {
+				p.Play()
+				<-p.Out()
+			}
+			if tst.seek > 0 {
+				if err := p.Seek(tst.seek); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tst.end {
+				for {
+					if _, ok := nextPacket(p); !ok {
+						break
+					}
+				}
+				if state := p.State(); state != apollo.IdleState {
+					t.Fatalf("expected state %s; got %s", apollo.IdleState, state)
+				}
+			}
+
+			// The codec position runs ahead of the output only by the few packets waiting to be sent.
+			if position := p.Position(); position < tst.position || position > tst.position+100*time.Millisecond {
+				t.Fatalf("expected position %s; got %s", tst.position, position)
+			}
+			if remaining := p.Remaining(); remaining > tst.remaining || remaining < tst.remaining-100*time.Millisecond {
+				t.Fatalf("expected remaining %s; got %s", tst.remaining, remaining)
+			}
+		})
+	}
+}
+
+func TestPlayer_RepeatMode(t *testing.T) {
+	type test struct {
+		mode apollo.RepeatMode
+		stop bool
+		// wait is the number of events to wait for.
+		wait  int
+		state apollo.PlayerState
+		// started holds the names of the first Playables started.
+		started []string
+	}
+
+	tests := map[string]test{
+		"repeat_one": {
+			mode:    apollo.RepeatOne,
+			wait:    7,
+			state:   apollo.PlayState,
+			started: []string{"a", "a", "a"},
+		},
+		"repeat_all": {
+			mode:    apollo.RepeatAll,
+			wait:    9,
+			state:   apollo.PlayState,
+			started: []string{"a", "b", "a", "b"},
+		},
+		"stop_after_current": {
+			mode:    apollo.RepeatAll,
+			stop:    true,
+			wait:    4,
+			state:   apollo.IdleState,
+			started: []string{"a"},
+		},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 4}, nil)
+			defer p.Close()
+			go func() {
+				for range p.Out() {
+				}
+			}()
+
+			rec := newRecorder()
+			p.Subscribe(rec.record)
+
+			p.Enqueue(testPlayable{name: "a", data: []byte("a")})
+			p.Enqueue(testPlayable{name: "b", data: []byte("b")})
+			p.SetRepeatMode(tst.mode)
+			p.SetStopAfterCurrent(tst.stop)
+			p.Play()
+
+			var started []string
+			for _, event := range rec.wait(tst.wait) {
+				if name, ok := strings.CutPrefix(event, "TrackStarted "); ok && len(started) < len(tst.started) {
+					started = append(started, name)
+				}
+			}
+
+			if !slices.Equal(started, tst.started) {
+				t.Fatalf("expected %v to be started; got %v", tst.started, started)
+			}
+			if state := p.State(); state != tst.state {
+				t.Fatalf("expected state %s; got %s", tst.state, state)
+			}
+		})
+	}
+}
+
+func TestPlayer_Close(t *testing.T) {
+	type test struct {
+		state  apollo.PlayerState
+		cancel bool
+	}
+
+	tests := map[string]test{
+		"idle_close":     {state: apollo.IdleState, cancel: false},
+		"idle_cancel":    {state: apollo.IdleState, cancel: true},
+		"playing_close":  {state: apollo.PlayState, cancel: false},
+		"playing_cancel": {state: apollo.PlayState, cancel: true},
+		"paused_close":   {state: apollo.PauseState, cancel: false},
+		"paused_cancel":  {state: apollo.PauseState, cancel: true},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			p := apollo.NewPlayerContext(ctx, apollo.PlayerConfig{PacketBuffer: 4}, nil)
+			defer p.Close()
+
+			if tst.state != apollo.IdleState {
+				p.Enqueue(testPlayable{data: make([]byte, 1<<20)})
+				p.Play()
+				<-p.Out()
+			}
+			if tst.state == apollo.PauseState {
+				p.Pause()
+				// The pause is only picked up once the packet being sent is read. Waiting between reads gives the
+				// request a chance to reach the playable listener, which would otherwise be kept busy sending.
+				deadline := time.After(time.Second)
+				for p.State() != apollo.PauseState {
+					select {
+					case <-time.After(time.Millisecond):
+					case <-deadline:
+						t.Fatal("expected the player to pause")
+					}
+					select {
+					case <-p.Out():
+					default:
+					}
+				}
+			}
+
+			if tst.cancel {
+				cancel()
+			} else {
+				go p.Close()
+			}
+
+			deadline := time.After(time.Second)
+			for {
+				select {
+				case _, ok := <-p.Out():
+					if !ok {
+						return
+					}
+				case <-deadline:
+					t.Fatal("expected the out channel to be closed")
+				}
+			}
+		})
+	}
+}
+
+// packetDuration is the media time each packet of a seekCodec stands for.
+const packetDuration = 20 * time.Millisecond
+
+// seekCodec is a SeekableCodec and TimedCodec that returns one byte of its stream per Read, each standing for
+// packetDuration.
+type seekCodec struct {
+	r        io.Reader
+	position time.Duration
+}
+
+func (c *seekCodec) Open(r io.Reader) error {
+	return c.OpenAt(r, 0)
+}
+
+func (c *seekCodec) OpenAt(r io.Reader, offset time.Duration) error {
+	c.r = r
+	c.position = offset
+
+	// Seeking past the end leaves nothing to read.
+	if _, err := io.CopyN(io.Discard, r, int64(offset/packetDuration)); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
+func (c *seekCodec) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p[:min(len(p), 1)])
+	c.position += time.Duration(n) * packetDuration
+	return n, err
+}
+
+func (c *seekCodec) Position() (time.Duration, bool) {
+	return c.position, true
+}
+
+func (c *seekCodec) Close() error {
+	return nil
+}
+
+// packets returns n packets for a seekCodec, each holding its index.
+func packets(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	return data
+}
+
+// opusStream returns an ogg opus stream of n packets of 20ms, each on a page of its own. The second byte of each packet
+// holds its index.
+func opusStream(n int) []byte {
+	var buf bytes.Buffer
+	e := ogg.NewEncoder(&buf, 1)
+	_ = e.WritePacket([]byte("OpusHead\x01\x02\x00\x00\x80\xbb\x00\x00\x00\x00\x00"), 0)
+	_ = e.WritePacket([]byte("OpusTags"), 0)
+	_ = e.Flush()
+	for i := 0; i < n; i++ {
+		_ = e.WritePacket([]byte{31 << 3, byte(i)}, int64(i+1)*960)
+		_ = e.Flush()
+	}
+	_ = e.Close()
+
+	return buf.Bytes()
+}
+
+// nextAudio reads from the out channel of p until a packet of opusStream arrives and returns its index. False is
+// returned if the output ends or stalls first.
+func nextAudio(p *apollo.Player) (int, bool) {
+	for {
+		select {
+		case packet, ok := <-p.Out():
+			if !ok {
+				return 0, false
+			}
+			if len(packet) == 2 && packet[0] == 31<<3 {
+				return int(packet[1]), true
+			}
+		case <-time.After(200 * time.Millisecond):
+			return 0, false
+		}
+	}
+}
+
+// nextPacket reads the next packet from the out channel of p and returns its first byte. False is returned if the
+// output ends or stalls first.
+func nextPacket(p *apollo.Player) (int, bool) {
+	select {
+	case packet, ok := <-p.Out():
+		if !ok || len(packet) == 0 {
+			return 0, false
+		}
+		return int(packet[0]), true
+	case <-time.After(200 * time.Millisecond):
+		return 0, false
+	}
+}
+
+// testPlayable is a Playable serving data.
+type testPlayable struct {
+	name        string
+	data        []byte
+	duration    time.Duration
+	contentType string
+	// downloads counts the calls to Download, if set.
+	downloads *atomic.Int64
+}
+
+func (t testPlayable) Name() string                { return t.name }
+func (t testPlayable) Artist() string              { return "test" }
+func (t testPlayable) Album() string               { return "test" }
+func (t testPlayable) Metadata() map[string]string { return nil }
+func (t testPlayable) Duration() time.Duration     { return t.duration }
+func (t testPlayable) Description() string         { return "test playable" }
+func (t testPlayable) Type() string                { return "test" }
+func (t testPlayable) ContentType() string         { return t.contentType }
+
+func (t testPlayable) Descriptor() apollo.Descriptor {
+	return apollo.Descriptor{Type: t.Type(), Ref: t.name}
+}
+
+// resolveTestPlayable recreates the testPlayables described by Descriptor.
+func resolveTestPlayable(d apollo.Descriptor) (apollo.Playable, error) {
+	return testPlayable{name: d.Ref}, nil
+}
+
+func (t testPlayable) Download() (io.ReadCloser, error) {
+	if t.downloads != nil {
+		t.downloads.Add(1)
+	}
+	return io.NopCloser(bytes.NewReader(t.data)), nil
+}
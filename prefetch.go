@@ -0,0 +1,201 @@
+package apollo
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+)
+
+// prefetch downloads, and if possible opens, a queue entry in the background so that it is ready to play as soon as
+// the current one ends.
+type prefetch struct {
+	index   int
+	version int64
+	pc      PlayableCodec
+	open    bool
+
+	lock      sync.Mutex
+	r         io.ReadCloser
+	opened    bool
+	err       error
+	cancelled bool
+	ready     chan struct{}
+}
+
+func (pf *prefetch) run(p *Player) {
+	defer close(pf.ready)
+
+	r, err := p.download(pf.pc.playable)
+	if err == nil {
+		// Looking up the loudness now starts measuring it if needed, so that it is likely known by the time the entry
+		// is played.
+		p.normalizationGain(pf.pc.playable)
+	}
+
+	pf.lock.Lock()
+	defer pf.lock.Unlock()
+
+	if pf.cancelled {
+		if r != nil {
+			_ = r.Close()
+		}
+		return
+	}
+
+	if err != nil {
+		pf.err = err
+		return
+	}
+
+	pf.r = r
+
+	if pf.open {
+		if err = pf.pc.codec.Open(r); err != nil {
+			_ = r.Close()
+			pf.r = nil
+			pf.err = err
+			return
+		}
+		pf.opened = true
+	}
+}
+
+// cancel releases everything the prefetch has acquired so far. A prefetch that is still downloading releases its
+// reader once the download returns, and never opens its codec.
+func (pf *prefetch) cancel() {
+	pf.lock.Lock()
+	defer pf.lock.Unlock()
+
+	if pf.cancelled {
+		return
+	}
+	pf.cancelled = true
+
+	if pf.opened {
+		_ = pf.pc.codec.Close()
+	}
+	if pf.r != nil {
+		_ = pf.r.Close()
+	}
+}
+
+// claim waits for the prefetch to finish and hands over its reader. The codec has already been opened on it if opened
+// is true. ok is false if the prefetch was cancelled in the meantime.
+func (pf *prefetch) claim(ctx context.Context) (r io.ReadCloser, opened bool, ok bool, err error) {
+	select {
+	case <-ctx.Done():
+		pf.cancel()
+		return nil, false, false, nil
+	case <-pf.ready:
+	}
+
+	pf.lock.Lock()
+	defer pf.lock.Unlock()
+
+	if pf.cancelled {
+		return nil, false, false, nil
+	}
+	// The caller owns the resources from here on, so a later cancel must not touch them.
+	pf.cancelled = true
+
+	return pf.r, pf.opened, true, pf.err
+}
+
+// upcoming returns the queue index that will be played once the current Playable finishes on its own.
+func (p *Player) upcoming() (int, bool) {
+	p.stateLock.RLock()
+	stop, i, repeatMode := p.stopAfterCurrent, p.cursor, p.repeatMode
+	p.stateLock.RUnlock()
+
+	if stop {
+		return 0, false
+	}
+
+	if repeatMode == RepeatOne {
+		i--
+	}
+	if repeatMode == RepeatAll && i >= p.queue.Len() {
+		i = 0
+	}
+
+	if i < 0 || i >= p.queue.Len() {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// maybePrefetch starts prefetching the upcoming queue entry once the current one is within the configured look-ahead
+// of its end. current is the PlayableCodec that is playing right now.
+func (p *Player) maybePrefetch(current PlayableCodec) {
+	if p.config.PrefetchLookAhead <= 0 || p.prefetched.Load() != nil || p.Remaining() > p.config.PrefetchLookAhead {
+		return
+	}
+
+	version := p.queueVersion.Load()
+	index, ok := p.upcoming()
+	if !ok {
+		return
+	}
+
+	pc, ok := p.queue.SafeGet(index)
+	if !ok {
+		return
+	}
+
+	pf := &prefetch{
+		index:   index,
+		version: version,
+		pc:      pc,
+		// A codec can only be open once, so sharing the instance with the current entry limits us to downloading.
+		open:  !sameCodec(pc.codec, current.codec),
+		ready: make(chan struct{}),
+	}
+
+	if !p.prefetched.CompareAndSwap(nil, pf) {
+		return
+	}
+
+	p.logger.Debug("prefetching "+pc.playable.Type(), slog.Any("playable", nameArtistAlbumType(pc.playable)))
+	go pf.run(p)
+}
+
+// claimPrefetch returns the prefetch for the queue entry at index, if there is a valid one. Any other prefetch is
+// cancelled.
+func (p *Player) claimPrefetch(index int) *prefetch {
+	pf := p.prefetched.Swap(nil)
+	if pf == nil {
+		return nil
+	}
+
+	if pf.index != index || pf.version != p.queueVersion.Load() {
+		pf.cancel()
+		return nil
+	}
+
+	return pf
+}
+
+// invalidatePrefetch cancels any prefetch in progress. It must be called whenever the queue is changed.
+func (p *Player) invalidatePrefetch() {
+	p.queueVersion.Add(1)
+
+	if pf := p.prefetched.Swap(nil); pf != nil {
+		pf.cancel()
+	}
+}
+
+// sameCodec reports whether a and b are the same Codec instance.
+func sameCodec(a, b Codec) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
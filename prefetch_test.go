@@ -0,0 +1,92 @@
+package apollo_test
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestPlayer_Prefetch(t *testing.T) {
+	type test struct {
+		shared bool
+		change bool
+		// opens is the number of times the codec of the second entry is opened while the first one still plays.
+		opens     int64
+		downloads int64
+	}
+
+	tests := map[string]test{
+		"unchanged":     {shared: false, change: false, opens: 1, downloads: 1},
+		"queue_changed": {shared: false, change: true, opens: 1, downloads: 2},
+		// The shared codec is still in use by the first entry, so the second one is only downloaded.
+		"shared_codec": {shared: true, change: false, opens: 1, downloads: 1},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 4, PrefetchLookAhead: time.Hour}, nil)
+			defer p.Close()
+
+			first := &openCounter{}
+			second := &openCounter{}
+			if tst.shared {
+				second = first
+			}
+
+			var downloads atomic.Int64
+			p.EnqueueWithCodec(testPlayable{name: "a", data: make([]byte, 40)}, first)
+			p.EnqueueWithCodec(testPlayable{name: "b", data: make([]byte, 40), downloads: &downloads}, second)
+			p.Play()
+
+			<-p.Out()
+			if !eventually(func() bool { return downloads.Load() == 1 }) {
+				t.Fatal("expected the second entry to be prefetched")
+			}
+			if opens := second.opens.Load(); opens != tst.opens {
+				t.Fatalf("expected %d opens while the first entry plays; got %d", tst.opens, opens)
+			}
+
+			if tst.change {
+				p.Enqueue(testPlayable{name: "c"})
+			}
+
+			go func() {
+				for range p.Out() {
+				}
+			}()
+			if !eventually(func() bool { return p.State() == apollo.IdleState }) {
+				t.Fatal("expected the queue to be played")
+			}
+
+			if got := downloads.Load(); got != tst.downloads {
+				t.Fatalf("expected %d downloads of the second entry; got %d", tst.downloads, got)
+			}
+		})
+	}
+}
+
+// openCounter is a NopCodec that counts the calls to Open.
+type openCounter struct {
+	apollo.NopCodec
+	opens atomic.Int64
+}
+
+func (c *openCounter) Open(r io.Reader) error {
+	c.opens.Add(1)
+	return c.NopCodec.Open(r)
+}
+
+// eventually reports whether f returns true within a second.
+func eventually(f func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if f() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return f()
+}
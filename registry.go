@@ -0,0 +1,203 @@
+package apollo
+
+import (
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Descriptor identifies a Playable well enough for a Registry to create it again, e.g. after a restart.
+type Descriptor struct {
+	// Type is the Type of the described Playable.
+	Type string `json:"type"`
+	// Ref locates the Playable within its type, e.g. a file path or a track ID.
+	Ref string `json:"ref"`
+}
+
+// Describable is implemented by Playables that can be recreated from a Descriptor.
+type Describable interface {
+	Descriptor() Descriptor
+}
+
+// PlayableResolver creates the Playable described by d.
+type PlayableResolver func(d Descriptor) (Playable, error)
+
+// CodecFactory creates a new Codec instance.
+type CodecFactory func() Codec
+
+// ContentTyped is implemented by Playables that know the MIME type of their downloaded data, e.g.
+// "audio/ogg; codecs=opus". It is used to pick a Codec for them automatically.
+type ContentTyped interface {
+	ContentType() string
+}
+
+// Registry knows how to recreate Playables and Codecs from their serialized form. Playables are resolved by their
+// Descriptor's Type, and Codecs are created by the name they were registered with. Content types can also be mapped to
+// codecs, which lets a Player pick the codec for every Playable on its own.
+type Registry struct {
+	lock         sync.RWMutex
+	playables    map[string]PlayableResolver
+	codecs       []namedCodec
+	contentTypes map[string]string
+	fallback     string
+}
+
+type namedCodec struct {
+	name    string
+	factory CodecFactory
+	// codecType is the concrete type created by factory, used to recognize its codecs.
+	codecType reflect.Type
+}
+
+// NewRegistry creates a Registry that can already resolve LocalFiles.
+func NewRegistry() *Registry {
+	r := &Registry{
+		playables:    make(map[string]PlayableResolver),
+		contentTypes: make(map[string]string),
+	}
+
+	r.RegisterPlayable(LocalFile{}.Type(), ResolveLocalFile)
+
+	return r
+}
+
+// RegisterPlayable sets the resolver used for Descriptors of typ, replacing any previous one.
+func (r *Registry) RegisterPlayable(typ string, resolver PlayableResolver) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.playables[typ] = resolver
+}
+
+// RegisterCodec makes Codecs created by factory available under name. Codecs of queue entries are recorded by the name
+// of the first registered factory that creates the same concrete type, so factories that create the same type with
+// different settings can't be told apart.
+func (r *Registry) RegisterCodec(name string, factory CodecFactory) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	codec := namedCodec{name: name, factory: factory, codecType: reflect.TypeOf(factory())}
+
+	for i := range r.codecs {
+		if r.codecs[i].name == name {
+			r.codecs[i] = codec
+			return
+		}
+	}
+
+	r.codecs = append(r.codecs, codec)
+}
+
+// RegisterContentType makes Playables of contentType use codecs registered under codecName. contentType may include a
+// codecs parameter, e.g. "audio/ogg; codecs=opus", which is preferred over a registration of the bare media type.
+func (r *Registry) RegisterContentType(contentType string, codecName string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.contentTypes[normalizeContentType(contentType)] = codecName
+}
+
+// SetFallbackCodec sets the codec used for Playables with a content type that isn't registered. Playables that don't
+// advertise a content type always use the player's default codec.
+func (r *Registry) SetFallbackCodec(codecName string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.fallback = codecName
+}
+
+// CodecFor creates a new Codec for playable based on its content type, and returns the name it was created by. ok is
+// false if playable doesn't advertise a content type, or if there is no codec for it.
+func (r *Registry) CodecFor(playable Playable) (codec Codec, name string, ok bool) {
+	typed, isTyped := playable.(ContentTyped)
+	if !isTyped || typed.ContentType() == "" {
+		return nil, "", false
+	}
+
+	contentType := normalizeContentType(typed.ContentType())
+
+	r.lock.RLock()
+	name, ok = r.contentTypes[contentType]
+	if !ok {
+		mediaType, _, _ := strings.Cut(contentType, ";")
+		name, ok = r.contentTypes[mediaType]
+	}
+	if !ok && r.fallback != "" {
+		name, ok = r.fallback, true
+	}
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, "", false
+	}
+
+	codec, err := r.NewCodec(name)
+	if err != nil {
+		return nil, "", false
+	}
+
+	return codec, name, true
+}
+
+// Resolve creates the Playable described by d.
+func (r *Registry) Resolve(d Descriptor) (Playable, error) {
+	r.lock.RLock()
+	resolver, ok := r.playables[d.Type]
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPlayable, d.Type)
+	}
+
+	return resolver(d)
+}
+
+// NewCodec creates a new Codec of the factory registered under name.
+func (r *Registry) NewCodec(name string) (Codec, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, codec := range r.codecs {
+		if codec.name == name {
+			return codec.factory(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+}
+
+// codecName returns the name of the factory that creates codecs like c.
+func (r *Registry) codecName(c Codec) (string, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, codec := range r.codecs {
+		if codec.codecType == reflect.TypeOf(c) {
+			return codec.name, true
+		}
+	}
+
+	return "", false
+}
+
+// ResolveLocalFile creates the LocalFile found at the path in d.Ref.
+func ResolveLocalFile(d Descriptor) (Playable, error) {
+	return NewLocalFile(d.Ref)
+}
+
+// normalizeContentType returns contentType in a canonical form, so that registrations and lookups compare equal. Only
+// the codecs parameter is kept.
+func normalizeContentType(contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	if codecs, ok := params["codecs"]; ok {
+		return mediaType + ";codecs=" + strings.ToLower(codecs)
+	}
+
+	return mediaType
+}
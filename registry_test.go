@@ -0,0 +1,83 @@
+package apollo_test
+
+import (
+	"testing"
+
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/ogg"
+)
+
+func TestNormalizeContentType(t *testing.T) {
+	type test struct {
+		contentType string
+		expected    string
+	}
+
+	tests := map[string]test{
+		"bare":       {"audio/ogg", "audio/ogg"},
+		"codecs":     {"audio/ogg; codecs=opus", "audio/ogg;codecs=opus"},
+		"case":       {"Audio/OGG; Codecs=Opus", "audio/ogg;codecs=opus"},
+		"spaces":     {"  audio/ogg ;codecs=opus  ", "audio/ogg;codecs=opus"},
+		"other_only": {"audio/mpeg; charset=binary", "audio/mpeg"},
+		"invalid":    {" Audio/Ogg; codecs= ", "audio/ogg; codecs="},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			if contentType := apollo.NormalizeContentType(tst.contentType); contentType != tst.expected {
+				t.Fatalf("expected %q; got %q", tst.expected, contentType)
+			}
+		})
+	}
+}
+
+func TestRegistry_CodecFor(t *testing.T) {
+	type test struct {
+		playable apollo.Playable
+		fallback string
+		name     string
+		ok       bool
+	}
+
+	tests := map[string]test{
+		"codecs":         {playable: testPlayable{contentType: "audio/ogg; codecs=opus"}, name: "opus", ok: true},
+		"case":           {playable: testPlayable{contentType: "AUDIO/OGG; CODECS=OPUS"}, name: "opus", ok: true},
+		"media_type":     {playable: testPlayable{contentType: "audio/ogg; codecs=vorbis"}, name: "ogg", ok: true},
+		"bare":           {playable: testPlayable{contentType: "audio/ogg"}, name: "ogg", ok: true},
+		"unknown":        {playable: testPlayable{contentType: "audio/mpeg"}},
+		"fallback":       {playable: testPlayable{contentType: "audio/mpeg"}, fallback: "nop", name: "nop", ok: true},
+		"no_type":        {playable: testPlayable{}, fallback: "nop"},
+		"not_typed":      {playable: untypedPlayable{testPlayable{contentType: "audio/ogg"}}, fallback: "nop"},
+		"unknown_codec":  {playable: testPlayable{contentType: "audio/flac"}},
+		"unknown_codecs": {playable: testPlayable{contentType: "audio/ogg; codecs=flac"}, name: "ogg", ok: true},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := apollo.NewRegistry()
+			reg.RegisterCodec("ogg", func() apollo.Codec { return ogg.NewDecoder() })
+			reg.RegisterCodec("opus", func() apollo.Codec { return ogg.NewDecoder().WithoutHeaders() })
+			reg.RegisterCodec("nop", func() apollo.Codec { return &apollo.NopCodec{} })
+			reg.RegisterContentType("audio/ogg", "ogg")
+			reg.RegisterContentType("Audio/Ogg; Codecs=Opus", "opus")
+			reg.RegisterContentType("audio/flac", "flac")
+			reg.SetFallbackCodec(tst.fallback)
+
+			codec, codecName, ok := reg.CodecFor(tst.playable)
+			if ok != tst.ok {
+				t.Fatalf("expected ok to be %t; got %t", tst.ok, ok)
+			}
+			if codecName != tst.name {
+				t.Fatalf("expected codec %q; got %q", tst.name, codecName)
+			}
+			if ok && codec == nil {
+				t.Fatal("expected a codec; got nil")
+			}
+		})
+	}
+}
+
+// untypedPlayable hides the ContentType of the Playable it wraps.
+type untypedPlayable struct {
+	apollo.Playable
+}
@@ -0,0 +1,204 @@
+package apollo
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// SinkPolicy decides what happens to packets for a Sink whose buffer is full.
+type SinkPolicy int
+
+const (
+	// SinkBlock holds the player back until the Sink has room again, which also holds back every other Sink.
+	SinkBlock SinkPolicy = iota
+	// SinkDrop drops packets that don't fit the Sink's buffer, so that a slow Sink doesn't affect the others.
+	SinkDrop
+)
+
+type SinkConfig struct {
+	// Buffer sets how many packets the Sink holds before its Policy kicks in.
+	// Defaults to 0.
+	Buffer int `json:"buffer"`
+
+	// Policy sets what happens to packets when the buffer is full.
+	// Defaults to SinkBlock.
+	Policy SinkPolicy `json:"policy"`
+}
+
+// Sink is one of several consumers of a Player's output, added with Player.AddSink. Every Sink receives every packet
+// that is sent while it is attached. Packets are shared between Sinks and must not be modified.
+type Sink struct {
+	out    chan []byte
+	policy SinkPolicy
+
+	// mu is held while a packet is sent, so that out isn't closed mid-send. removed is closed first to abort a blocked
+	// send.
+	mu      sync.Mutex
+	closed  bool
+	removed chan struct{}
+	once    sync.Once
+
+	dropped atomic.Int64
+}
+
+// Out returns the channel the Sink's packets are sent on. It is closed once the Sink is removed or the player shuts
+// down.
+func (s *Sink) Out() <-chan []byte {
+	return s.out
+}
+
+// Dropped returns the number of packets that were dropped because the Sink's buffer was full. Always 0 with SinkBlock.
+func (s *Sink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// send sends v according to the Sink's policy. It returns once v was sent or dropped, the Sink was removed, or ctx is
+// done.
+func (s *Sink) send(ctx context.Context, v []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if s.policy == SinkDrop {
+		select {
+		case s.out <- v:
+		default:
+			s.dropped.Add(1)
+		}
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-s.removed:
+	case s.out <- v:
+	}
+}
+
+// close closes the Sink's channel once no packet is being sent to it.
+func (s *Sink) close() {
+	s.once.Do(func() {
+		close(s.removed)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.closed = true
+		close(s.out)
+	})
+}
+
+// sinkSet holds the Sinks of a Player. changed is closed and replaced whenever a Sink is added, so that the distributor
+// can wait for one.
+type sinkSet struct {
+	mu      sync.Mutex
+	sinks   []*Sink
+	changed chan struct{}
+	// closed is set once the player's out channel is closed, after which Sinks are closed as soon as they are added.
+	closed bool
+	start  sync.Once
+}
+
+func newSinkSet() *sinkSet {
+	return &sinkSet{changed: make(chan struct{})}
+}
+
+func (ss *sinkSet) add(s *Sink) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.closed {
+		s.close()
+		return
+	}
+
+	ss.sinks = append(ss.sinks, s)
+	close(ss.changed)
+	ss.changed = make(chan struct{})
+}
+
+func (ss *sinkSet) remove(s *Sink) {
+	ss.mu.Lock()
+	for i, sink := range ss.sinks {
+		if sink == s {
+			ss.sinks = append(ss.sinks[:i:i], ss.sinks[i+1:]...)
+			break
+		}
+	}
+	ss.mu.Unlock()
+
+	s.close()
+}
+
+// wait returns the current Sinks, blocking until there is at least one. It returns nil if ctx is done first.
+func (ss *sinkSet) wait(ctx context.Context) []*Sink {
+	for {
+		ss.mu.Lock()
+		sinks, changed := ss.sinks, ss.changed
+		ss.mu.Unlock()
+
+		if len(sinks) > 0 {
+			return sinks
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// closeAll closes every Sink, including those added later.
+func (ss *sinkSet) closeAll() {
+	ss.mu.Lock()
+	sinks := ss.sinks
+	ss.sinks = nil
+	ss.closed = true
+	ss.mu.Unlock()
+
+	for _, s := range sinks {
+		s.close()
+	}
+}
+
+// AddSink attaches a new Sink to the player, which receives every packet sent from then on. Sinks can be added and
+// removed at any time without interrupting playback. Once the first Sink is added, the player distributes the out
+// channel to its Sinks itself, so Out must not be read anymore. While no Sink is attached, the player waits for one
+// like it would for an unread out channel.
+func (p *Player) AddSink(config SinkConfig) *Sink {
+	s := &Sink{
+		out:     make(chan []byte, max(config.Buffer, 0)),
+		policy:  config.Policy,
+		removed: make(chan struct{}),
+	}
+
+	p.sinks.add(s)
+	p.sinks.start.Do(func() {
+		go p.distribute()
+	})
+
+	return s
+}
+
+// RemoveSink detaches s from the player and closes its channel. Packets still buffered in s can be read until then.
+func (p *Player) RemoveSink(s *Sink) {
+	if s != nil {
+		p.sinks.remove(s)
+	}
+}
+
+// distribute sends everything on the out channel to the player's Sinks, until the out channel is closed.
+func (p *Player) distribute() {
+	defer p.sinks.closeAll()
+
+	for v := range p.outChan {
+		for _, s := range p.sinks.wait(p.ctx) {
+			s.send(p.ctx, v)
+		}
+	}
+}
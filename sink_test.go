@@ -0,0 +1,96 @@
+package apollo_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo"
+)
+
+func TestPlayer_AddSink(t *testing.T) {
+	type test struct {
+		config apollo.SinkConfig
+		// before is how many of the 10 packets the fast Sink receives before the slow one is removed.
+		before  int64
+		slow    int
+		dropped int64
+	}
+
+	tests := map[string]test{
+		"drop":     {config: apollo.SinkConfig{Buffer: 2, Policy: apollo.SinkDrop}, before: 10, slow: 2, dropped: 8},
+		"block":    {config: apollo.SinkConfig{Buffer: 2, Policy: apollo.SinkBlock}, before: 3, slow: 2},
+		"buffered": {config: apollo.SinkConfig{Buffer: 10, Policy: apollo.SinkBlock}, before: 10, slow: 10},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := apollo.NewPlayer(apollo.PlayerConfig{PacketBuffer: 4}, nil)
+			defer p.Close()
+
+			fast := p.AddSink(apollo.SinkConfig{})
+			slow := p.AddSink(tst.config)
+
+			var received atomic.Int64
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range fast.Out() {
+					received.Add(1)
+				}
+			}()
+
+			p.Enqueue(testPlayable{data: make([]byte, 40)})
+			p.Play()
+
+			time.Sleep(100 * time.Millisecond)
+			if received.Load() != tst.before {
+				t.Fatalf("expected %d packets before removing the slow sink; got %d", tst.before, received.Load())
+			}
+
+			p.RemoveSink(slow)
+
+			// Packets still buffered in a removed Sink can be read.
+			var slowReceived int
+			for range slow.Out() {
+				slowReceived++
+			}
+			if slowReceived != tst.slow {
+				t.Fatalf("expected the slow sink to receive %d packets; got %d", tst.slow, slowReceived)
+			}
+			if slow.Dropped() != tst.dropped {
+				t.Fatalf("expected %d dropped packets; got %d", tst.dropped, slow.Dropped())
+			}
+
+			// Removing the slow Sink lets the rest through.
+			time.Sleep(100 * time.Millisecond)
+			if received.Load() != 10 {
+				t.Fatalf("expected 10 packets; got %d", received.Load())
+			}
+
+			_ = p.Close()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("expected the sink to be closed with the player")
+			}
+		})
+	}
+}
+
+func TestPlayer_AddSink_Closed(t *testing.T) {
+	p := apollo.NewPlayer(apollo.PlayerConfig{}, nil)
+	_ = p.AddSink(apollo.SinkConfig{})
+	_ = p.Close()
+
+	// Whether or not the distributor has stopped yet, the Sink is closed without receiving anything.
+	s := p.AddSink(apollo.SinkConfig{})
+	select {
+	case _, ok := <-s.Out():
+		if ok {
+			t.Fatal("expected no packets")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the sink to be closed")
+	}
+}
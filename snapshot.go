@@ -0,0 +1,144 @@
+package apollo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/eolso/threadsafe"
+)
+
+// Snapshot is the serializable state of a Player's queue. Shuffling is captured by the order of the entries.
+type Snapshot struct {
+	Entries []EntrySnapshot `json:"entries"`
+	// Cursor is the index of the entry that playback resumes from. If something was playing when the snapshot was
+	// taken, it is that entry.
+	Cursor           int        `json:"cursor"`
+	RepeatMode       RepeatMode `json:"repeat_mode"`
+	StopAfterCurrent bool       `json:"stop_after_current"`
+}
+
+// EntrySnapshot is the serializable form of a queue entry.
+type EntrySnapshot struct {
+	Playable Descriptor `json:"playable"`
+	// Codec is the name the entry's codec is registered with. It is empty if the entry uses the player's default codec,
+	// or if its codec isn't registered.
+	Codec string  `json:"codec,omitempty"`
+	Gain  float64 `json:"gain,omitempty"`
+}
+
+// Snapshot captures the queue, cursor and repeat state of the player. reg, or the player's registry if reg is nil, is
+// used to name the codecs of queue entries; entries whose codec isn't registered are recorded with the default codec.
+// Playables that don't implement Describable can't be recreated and are left out.
+func (p *Player) Snapshot(reg *Registry) Snapshot {
+	if reg == nil {
+		reg = p.registry
+	}
+
+	// Everything is read at once, so that the state listener can't move the cursor in between.
+	p.stateLock.RLock()
+	state, cursor := p.currentState, p.cursor
+	// GetAll returns the backing array of the queue, which inserts change in place, so entries are copied one by one.
+	var pcs []PlayableCodec
+	for pc, ok := p.queue.SafeGet(0); ok; pc, ok = p.queue.SafeGet(len(pcs)) {
+		pcs = append(pcs, pc)
+	}
+	s := Snapshot{
+		RepeatMode:       p.repeatMode,
+		StopAfterCurrent: p.stopAfterCurrent,
+	}
+	p.stateLock.RUnlock()
+
+	// While something is playing, the cursor already points past it.
+	if (state == PlayState || state == PauseState) && cursor > 0 && cursor <= len(pcs) {
+		cursor--
+	}
+
+	s.Entries = make([]EntrySnapshot, 0, len(pcs))
+	for i, pc := range pcs {
+		if i == cursor {
+			s.Cursor = len(s.Entries)
+		}
+
+		describable, ok := pc.playable.(Describable)
+		if !ok {
+			p.logger.Warn("leaving "+pc.playable.Type()+" out of snapshot, it can't be described",
+				slog.Any("playable", nameArtistAlbumType(pc.playable)),
+			)
+			continue
+		}
+
+		entry := EntrySnapshot{Playable: describable.Descriptor(), Codec: pc.codecName, Gain: pc.gain}
+		if entry.Codec == "" && !sameCodec(pc.codec, p.codec) && reg != nil {
+			entry.Codec, _ = reg.codecName(pc.codec)
+		}
+
+		s.Entries = append(s.Entries, entry)
+	}
+
+	if cursor >= len(pcs) {
+		s.Cursor = len(s.Entries)
+	}
+
+	return s
+}
+
+// Restore replaces the queue with the entries of s and applies its cursor and repeat state. If something is playing, it
+// is skipped and playback continues from the restored cursor. Entries are recreated through reg, the player's registry
+// if reg is nil, or NewRegistry if neither is set. Entries that fail to resolve are left out and reported in the
+// returned error, while the rest of the queue is still restored.
+func (p *Player) Restore(s Snapshot, reg *Registry) error {
+	if reg == nil {
+		reg = p.registry
+	}
+	if reg == nil {
+		reg = NewRegistry()
+	}
+
+	var errs []error
+	queue := threadsafe.Slice[PlayableCodec]{}
+	cursor := 0
+
+	for i, entry := range s.Entries {
+		if i == s.Cursor {
+			cursor = queue.Len()
+		}
+
+		playable, err := reg.Resolve(entry.Playable)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("entry %d: %w", i, err))
+			continue
+		}
+
+		codec := p.codec
+		if entry.Codec != "" {
+			if codec, err = reg.NewCodec(entry.Codec); err != nil {
+				errs = append(errs, fmt.Errorf("entry %d: %w", i, err))
+				continue
+			}
+		}
+
+		queue.Append(PlayableCodec{playable: playable, codec: codec, gain: entry.Gain, codecName: entry.Codec})
+	}
+
+	if s.Cursor >= len(s.Entries) {
+		cursor = queue.Len()
+	}
+
+	p.stateLock.Lock()
+	playing := p.currentState == PlayState || p.currentState == PauseState
+	p.queue = &queue
+	p.cursor = cursor
+	p.repeatMode = s.RepeatMode
+	p.stopAfterCurrent = s.StopAfterCurrent
+	// The play context is only cancelled while playing, as an idle listener would use it for the next Playable.
+	if playing && p.playCancel != nil {
+		p.playCancel()
+	}
+	p.stateLock.Unlock()
+	p.invalidatePrefetch()
+
+	p.emit(PlayerEvent{Type: QueueChanged})
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package apollo_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/ogg"
+)
+
+func TestPlayer_Restore(t *testing.T) {
+	type test struct {
+		snapshot apollo.Snapshot
+		expected apollo.Snapshot
+		wantErr  bool
+	}
+
+	a := apollo.EntrySnapshot{Playable: apollo.Descriptor{Type: "test", Ref: "a"}}
+	b := apollo.EntrySnapshot{Playable: apollo.Descriptor{Type: "test", Ref: "b"}, Codec: "ogg"}
+	c := apollo.EntrySnapshot{Playable: apollo.Descriptor{Type: "test", Ref: "c"}, Gain: -3}
+	unknown := apollo.EntrySnapshot{Playable: apollo.Descriptor{Type: "unknown", Ref: "x"}}
+	unknownCodec := apollo.EntrySnapshot{Playable: apollo.Descriptor{Type: "test", Ref: "y"}, Codec: "unknown"}
+
+	tests := map[string]test{
+		"empty": {
+			snapshot: apollo.Snapshot{Entries: []apollo.EntrySnapshot{}},
+			expected: apollo.Snapshot{Entries: []apollo.EntrySnapshot{}},
+		},
+		"entries": {
+			snapshot: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a, b, c}, Cursor: 1},
+			expected: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a, b, c}, Cursor: 1},
+		},
+		"cursor_at_end": {
+			snapshot: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a, b}, Cursor: 2},
+			expected: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a, b}, Cursor: 2},
+		},
+		"repeat": {
+			snapshot: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a}, RepeatMode: apollo.RepeatAll,
+				StopAfterCurrent: true},
+			expected: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a}, RepeatMode: apollo.RepeatAll,
+				StopAfterCurrent: true},
+		},
+		"unresolvable": {
+			snapshot: apollo.Snapshot{Entries: []apollo.EntrySnapshot{unknown, a, unknownCodec, c}, Cursor: 3},
+			expected: apollo.Snapshot{Entries: []apollo.EntrySnapshot{a, c}, Cursor: 1},
+			wantErr:  true,
+		},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := apollo.NewRegistry()
+			reg.RegisterPlayable("test", resolveTestPlayable)
+			reg.RegisterCodec("ogg", func() apollo.Codec { return ogg.NewDecoder() })
+
+			p := apollo.NewPlayer(apollo.PlayerConfig{}, nil)
+			defer p.Close()
+
+			if err := p.Restore(tst.snapshot, reg); (err != nil) != tst.wantErr {
+				t.Fatalf("expected error to be %t; got %v", tst.wantErr, err)
+			}
+
+			// The snapshot has to survive being stored as JSON.
+			data, err := json.Marshal(p.Snapshot(reg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var snapshot apollo.Snapshot
+			if err = json.Unmarshal(data, &snapshot); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(snapshot, tst.expected) {
+				t.Fatalf("expected %+v; got %+v", tst.expected, snapshot)
+			}
+		})
+	}
+}
+
+func TestPlayer_Snapshot(t *testing.T) {
+	reg := apollo.NewRegistry()
+	reg.RegisterPlayable("test", resolveTestPlayable)
+	reg.RegisterCodec("ogg", func() apollo.Codec { return ogg.NewDecoder() })
+
+	p := apollo.NewPlayer(apollo.PlayerConfig{}, nil)
+	defer p.Close()
+
+	p.Enqueue(testPlayable{name: "a"})
+	p.EnqueueWithCodec(testPlayable{name: "b"}, ogg.NewDecoder())
+	p.Enqueue(undescribedPlayable{testPlayable{name: "c"}})
+	p.EnqueueWithGain(testPlayable{name: "d"}, nil, 2)
+	p.SetRepeatMode(apollo.RepeatOne)
+
+	expected := apollo.Snapshot{
+		Entries: []apollo.EntrySnapshot{
+			{Playable: apollo.Descriptor{Type: "test", Ref: "a"}},
+			{Playable: apollo.Descriptor{Type: "test", Ref: "b"}, Codec: "ogg"},
+			{Playable: apollo.Descriptor{Type: "test", Ref: "d"}, Gain: 2},
+		},
+		RepeatMode: apollo.RepeatOne,
+	}
+
+	if snapshot := p.Snapshot(reg); !reflect.DeepEqual(snapshot, expected) {
+		t.Fatalf("expected %+v; got %+v", expected, snapshot)
+	}
+}
+
+// undescribedPlayable hides the Descriptor of the Playable it wraps.
+type undescribedPlayable struct {
+	apollo.Playable
+}
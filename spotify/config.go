@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+
+	"github.com/olympus-go/apollo/cache"
 )
 
 type SessionConfig struct {
@@ -20,6 +22,11 @@ type SessionConfig struct {
 	// Defaults to ${HOME}/.apollo/spotify/cache/ on linux/macos and %userprofile%\AppData\local\apollo\spotify\cache\ on windows.
 	CacheDir string `json:"cache_dir"`
 
+	// Cache sets a cache to use instead of opening one from CacheSize and CacheDir, e.g. to share the cache of an
+	// apollo.Player.
+	// Defaults to nil.
+	Cache *cache.Cache `json:"-"`
+
 	// OAuthCallback sets the callback address for oauth logins
 	// Defaults to "" (http://localhost:8888/callback).
 	OAuthCallback string `json:"oauth_callback"`
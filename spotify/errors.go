@@ -9,3 +9,4 @@ var ErrPlayerCommandTimeout = errors.New("player command timed out")
 var ErrTokenNotFound = errors.New("spotify: auth token not found")
 var ErrPlayerAlreadyLoggedIn = errors.New("spotify: player already logged in")
 var ErrEmptySearchResponse = errors.New("spotify: search yielded no results")
+var ErrNotLoggedIn = errors.New("spotify: session is not logged in")
@@ -0,0 +1,27 @@
+package spotify
+
+import (
+	"io"
+
+	"github.com/olympus-go/apollo"
+)
+
+// This file exposes unexported parts of the package to its tests.
+
+type Normalization = normalization
+
+func (n *normalization) Read(r io.ReadSeeker) {
+	n.read(r)
+}
+
+func (n *normalization) Meta() map[string]string {
+	return n.meta()
+}
+
+func (n *normalization) Load(meta map[string]string) {
+	n.load(meta)
+}
+
+func (n *normalization) Get() (apollo.Loudness, bool) {
+	return n.get()
+}
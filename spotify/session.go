@@ -9,6 +9,8 @@ import (
 	"github.com/eolso/librespot-golang/librespot"
 	"github.com/eolso/librespot-golang/librespot/core"
 	"github.com/eolso/librespot-golang/librespot/utils"
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/cache"
 )
 
 // targetCodecs sets the order priority of codecs to fetch. TODO enable setting this.
@@ -34,6 +36,7 @@ var targetCodecs = []Spotify.AudioFile_Format{
 type Session struct {
 	config SessionConfig
 	client *core.Session
+	cache  *cache.Cache
 	logger *slog.Logger
 }
 
@@ -56,6 +59,15 @@ func NewSession(config SessionConfig, h slog.Handler) *Session {
 		}
 	}
 
+	if config.Cache != nil {
+		session.cache = config.Cache
+	} else if config.CacheSize > 0 && config.CacheDir != "" {
+		var err error
+		if session.cache, err = cache.New(config.CacheDir, config.CacheSize); err != nil {
+			session.logger.Error("failed to open song cache, caching is disabled", slog.String("error", err.Error()))
+		}
+	}
+
 	return &session
 }
 
@@ -95,7 +107,29 @@ func (s *Session) LoginWithToken(deviceName string, token string) error {
 
 func (s *Session) GetTrackById(id string) (Track, error) {
 	track, err := s.client.Mercury().GetTrack(utils.Base62ToHex(id))
-	return Track{spotifyTrack: track, player: s.client.Player()}, err
+	return Track{
+		spotifyTrack:  track,
+		player:        s.client.Player(),
+		cache:         s.cache,
+		normalization: &normalization{},
+		comments:      &vorbisComments{},
+	}, err
+}
+
+// ResolveTrack gets the track described by d. It can be registered as the apollo.PlayableResolver for spotify tracks:
+//
+//	registry.RegisterPlayable((&spotify.Track{}).Type(), session.ResolveTrack)
+func (s *Session) ResolveTrack(d apollo.Descriptor) (apollo.Playable, error) {
+	if !s.LoggedIn() {
+		return nil, ErrNotLoggedIn
+	}
+
+	track, err := s.GetTrackById(d.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &track, nil
 }
 
 func (s *Session) GetArtistById(id string) (Artist, error) {
@@ -1,19 +1,36 @@
 package spotify
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"maps"
+	"math"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eolso/librespot-golang/Spotify"
 	"github.com/eolso/librespot-golang/librespot/player"
 	"github.com/eolso/librespot-golang/librespot/utils"
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/cache"
+	"github.com/olympus-go/apollo/ogg/vorbis"
 )
 
+// normalizationOffset is where the normalization data is found in the header that precedes ogg audio files, relative to
+// the start of the audio.
+const normalizationOffset = 144 - 167
+
 type Track struct {
 	spotifyTrack *Spotify.Track
 	player       *player.Player
+	cache        *cache.Cache
+	// normalization is shared by all copies of the Track, since it is only known once the track has been downloaded.
+	normalization *normalization
+	// comments is shared the same way, holding the vorbis comments of the downloaded audio file.
+	comments *vorbisComments
 
 	customName        string
 	customArtist      string
@@ -42,14 +59,25 @@ func (t *Track) Artist() string {
 	return t.spotifyTrack.Artist[0].GetName()
 }
 
+// Metadata returns the vorbis comments of the track's audio file, keyed by their lowercase names. It is nil until the
+// track has been downloaded in an ogg format.
 func (t *Track) Metadata() map[string]string {
-	return nil
+	if t.comments == nil {
+		return nil
+	}
+
+	return t.comments.get()
 }
 
 func (t *Track) Id() string {
 	return utils.ConvertTo62(t.spotifyTrack.GetGid())
 }
 
+// Descriptor describes the track by its ID, so that it can be resolved again with Session.ResolveTrack.
+func (t *Track) Descriptor() apollo.Descriptor {
+	return apollo.Descriptor{Type: t.Type(), Ref: t.Id()}
+}
+
 func (t *Track) Description() string {
 	if len(t.customDescription) > 0 {
 		return t.customDescription
@@ -90,7 +118,89 @@ func (t *Track) Type() string {
 	return "spotify track"
 }
 
+// Cached reports whether the track's downloads go through the session's cache, so that an apollo.Player doesn't cache
+// them again.
+func (t *Track) Cached() bool {
+	return t.cache != nil
+}
+
+// Download returns the decrypted audio stream of the track. If the session has a cache configured, the stream is served
+// from and recorded to it.
 func (t *Track) Download() (io.ReadCloser, error) {
+	if t.cache == nil {
+		return t.download()
+	}
+
+	// The normalization data is in a header that isn't part of the audio stream, so it is stored with the cache entry.
+	r, meta, err := t.cache.OpenMeta(t.Type()+":"+t.Id(), func() (io.ReadCloser, map[string]string, error) {
+		r, err := t.download()
+		if err != nil || t.normalization == nil {
+			return r, nil, err
+		}
+
+		return r, t.normalization.meta(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache hits don't go through download, so their normalization and comments are restored here.
+	if t.normalization != nil {
+		t.normalization.load(meta)
+	}
+	if rs, ok := r.(io.ReadSeeker); ok && t.comments != nil && isVorbis(t.selectFile()) {
+		t.comments.read(rs)
+	}
+
+	return r, nil
+}
+
+func (t *Track) download() (io.ReadCloser, error) {
+	selectedFile := t.selectFile()
+	if selectedFile == nil {
+		return nil, fmt.Errorf("failed to fetch track data %s", t.Id())
+	}
+
+	audioFile, err := t.player.LoadTrack(selectedFile, t.spotifyTrack.GetGid())
+	if err != nil {
+		return nil, err
+	}
+
+	if isVorbis(selectedFile) {
+		if t.normalization != nil {
+			t.normalization.read(audioFile)
+		}
+		if t.comments != nil {
+			t.comments.read(audioFile)
+		}
+	}
+
+	return audioFile, nil
+}
+
+// ContentType returns the MIME type of the audio file that Download fetches, or "" if the track has no audio files.
+func (t *Track) ContentType() string {
+	file := t.selectFile()
+	if file == nil {
+		return ""
+	}
+
+	switch file.GetFormat() {
+	case Spotify.AudioFile_OGG_VORBIS_96, Spotify.AudioFile_OGG_VORBIS_160, Spotify.AudioFile_OGG_VORBIS_320:
+		return "audio/ogg; codecs=vorbis"
+	case Spotify.AudioFile_MP3_96, Spotify.AudioFile_MP3_160, Spotify.AudioFile_MP3_160_ENC, Spotify.AudioFile_MP3_256,
+		Spotify.AudioFile_MP3_320:
+		return "audio/mpeg"
+	case Spotify.AudioFile_AAC_160, Spotify.AudioFile_AAC_320, Spotify.AudioFile_MP4_128,
+		Spotify.AudioFile_MP4_128_DUAL:
+		return "audio/mp4"
+	default:
+		return ""
+	}
+}
+
+// selectFile picks the audio file to download, preferring the formats in targetCodecs. Returns nil if there is none.
+func (t *Track) selectFile() *Spotify.AudioFile {
 	var selectedFile *Spotify.AudioFile
 
 	audioFiles := t.spotifyTrack.GetFile()
@@ -107,7 +217,7 @@ func (t *Track) Download() (io.ReadCloser, error) {
 
 	// All alternatives tried, still no files
 	if len(audioFiles) == 0 {
-		return nil, fmt.Errorf("failed to fetch track data %s", t.Id())
+		return nil
 	}
 
 	// Try and grab a desired codec first
@@ -128,7 +238,17 @@ func (t *Track) Download() (io.ReadCloser, error) {
 		selectedFile = audioFiles[0]
 	}
 
-	return t.player.LoadTrack(selectedFile, t.spotifyTrack.GetGid())
+	return selectedFile
+}
+
+// Loudness returns the loudness derived from spotify's normalization data. It is only known once the track has been
+// downloaded in an ogg format, either from spotify or from the session's cache.
+func (t *Track) Loudness() (apollo.Loudness, bool) {
+	if t.normalization == nil {
+		return apollo.Loudness{}, false
+	}
+
+	return t.normalization.get()
 }
 
 func (t *Track) SetCustomName(name string) {
@@ -150,3 +270,116 @@ func (t *Track) SetCustomAlbum(album string) {
 func (t *Track) SetCustomImage(image string) {
 	t.customImage = image
 }
+
+// normalization holds the loudness read from the header of a spotify audio file.
+type normalization struct {
+	lock     sync.Mutex
+	loudness apollo.Loudness
+	ok       bool
+}
+
+// read reads the track gain and peak from the header of r, leaving r at the start of the audio.
+func (n *normalization) read(r io.ReadSeeker) {
+	if _, err := r.Seek(normalizationOffset, io.SeekStart); err != nil {
+		return
+	}
+	defer func() { _, _ = r.Seek(0, io.SeekStart) }()
+
+	// track gain in dB, track peak, album gain in dB and album peak, as little endian float32s.
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return
+	}
+
+	gain := math.Float32frombits(binary.LittleEndian.Uint32(buf[0:]))
+	peak := math.Float32frombits(binary.LittleEndian.Uint32(buf[4:]))
+	if math.IsNaN(float64(gain)) || math.IsInf(float64(gain), 0) {
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	// Spotify's gain brings tracks to -14 LUFS.
+	n.loudness = apollo.Loudness{Integrated: -14 - float64(gain), Peak: float64(peak)}
+	n.ok = true
+}
+
+// meta returns the loudness as cache metadata, or nil if it isn't known.
+func (n *normalization) meta() map[string]string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if !n.ok {
+		return nil
+	}
+
+	return map[string]string{
+		"integrated": strconv.FormatFloat(n.loudness.Integrated, 'g', -1, 64),
+		"peak":       strconv.FormatFloat(n.loudness.Peak, 'g', -1, 64),
+	}
+}
+
+// load sets the loudness from cache metadata created by meta, if it holds any.
+func (n *normalization) load(meta map[string]string) {
+	integrated, err := strconv.ParseFloat(meta["integrated"], 64)
+	if err != nil {
+		return
+	}
+	peak, _ := strconv.ParseFloat(meta["peak"], 64)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.loudness = apollo.Loudness{Integrated: integrated, Peak: peak}
+	n.ok = true
+}
+
+func (n *normalization) get() (apollo.Loudness, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.loudness, n.ok
+}
+
+// vorbisComments holds the comments read from the vorbis headers of a spotify audio file.
+type vorbisComments struct {
+	lock sync.Mutex
+	tags map[string]string
+}
+
+// read parses the vorbis headers at the start of r, leaving r at the start of the audio.
+func (c *vorbisComments) read(r io.ReadSeeker) {
+	defer func() { _, _ = r.Seek(0, io.SeekStart) }()
+
+	_, comment, err := vorbis.ReadHeaders(r)
+	if err != nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.tags = comment.Map()
+}
+
+func (c *vorbisComments) get() map[string]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return maps.Clone(c.tags)
+}
+
+// isVorbis reports whether file is one of spotify's ogg vorbis formats.
+func isVorbis(file *Spotify.AudioFile) bool {
+	if file == nil {
+		return false
+	}
+
+	switch file.GetFormat() {
+	case Spotify.AudioFile_OGG_VORBIS_96, Spotify.AudioFile_OGG_VORBIS_160, Spotify.AudioFile_OGG_VORBIS_320:
+		return true
+	default:
+		return false
+	}
+}
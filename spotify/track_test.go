@@ -0,0 +1,116 @@
+package spotify_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/olympus-go/apollo"
+	"github.com/olympus-go/apollo/spotify"
+)
+
+func TestNormalization_Read(t *testing.T) {
+	type test struct {
+		header   []byte
+		expected apollo.Loudness
+		ok       bool
+	}
+
+	tests := map[string]test{
+		"valid":     {header: genHeader(-3.5, 0.5), expected: apollo.Loudness{Integrated: -10.5, Peak: 0.5}, ok: true},
+		"louder":    {header: genHeader(4, 1), expected: apollo.Loudness{Integrated: -18, Peak: 1}, ok: true},
+		"nan_gain":  {header: genHeader(float32(math.NaN()), 0.5), ok: false},
+		"inf_gain":  {header: genHeader(float32(math.Inf(1)), 0.5), ok: false},
+		"no_header": {header: nil, ok: false},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			audio := []byte("OggS")
+			r := &audioFile{Reader: bytes.NewReader(append(tst.header, audio...)), header: int64(len(tst.header))}
+
+			n := &spotify.Normalization{}
+			n.Read(r)
+
+			l, ok := n.Get()
+			if ok != tst.ok {
+				t.Fatalf("expected ok to be %t; got %t", tst.ok, ok)
+			}
+			if l != tst.expected {
+				t.Fatalf("expected loudness %+v; got %+v", tst.expected, l)
+			}
+
+			// The audio has to be read from its start afterward.
+			if got, _ := io.ReadAll(r); !bytes.Equal(got, audio) {
+				t.Fatalf("expected to read %q after the header; got %q", audio, got)
+			}
+		})
+	}
+}
+
+func TestNormalization_Meta(t *testing.T) {
+	type test struct {
+		loudness apollo.Loudness
+		known    bool
+		meta     map[string]string
+	}
+
+	tests := map[string]test{
+		"known":   {loudness: apollo.Loudness{Integrated: -10.5, Peak: 0.5}, known: true},
+		"unknown": {known: false},
+		"invalid": {known: false, meta: map[string]string{"integrated": "loud"}},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			n := &spotify.Normalization{}
+			if tst.known {
+				n.Load(map[string]string{"integrated": "-10.5", "peak": "0.5"})
+			}
+
+			meta := tst.meta
+			if meta == nil {
+				meta = n.Meta()
+			}
+
+			restored := &spotify.Normalization{}
+			restored.Load(meta)
+
+			l, ok := restored.Get()
+			if ok != tst.known {
+				t.Fatalf("expected ok to be %t; got %t", tst.known, ok)
+			}
+			if l != tst.loudness {
+				t.Fatalf("expected loudness %+v; got %+v", tst.loudness, l)
+			}
+		})
+	}
+}
+
+// audioFile is an io.ReadSeeker that behaves like spotify's audio files, whose offsets are relative to the start of
+// the audio that follows a header.
+type audioFile struct {
+	*bytes.Reader
+	header int64
+}
+
+func (f *audioFile) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		offset += f.header
+	}
+
+	n, err := f.Reader.Seek(offset, whence)
+
+	return n - f.header, err
+}
+
+// genHeader returns a 167 byte header holding the track gain and peak at the offset spotify stores them at.
+func genHeader(gain, peak float32) []byte {
+	header := make([]byte, 167)
+	binary.LittleEndian.PutUint32(header[144:], math.Float32bits(gain))
+	binary.LittleEndian.PutUint32(header[148:], math.Float32bits(peak))
+
+	return header
+}
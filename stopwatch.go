@@ -0,0 +1,62 @@
+package apollo
+
+import (
+	"sync"
+	"time"
+)
+
+// stopwatch measures the playback time of the current Playable, excluding any time spent paused.
+type stopwatch struct {
+	lock      sync.Mutex
+	offset    time.Duration
+	started   time.Time
+	pausedAt  time.Time
+	pausedFor time.Duration
+}
+
+// Reset restarts the stopwatch as if playback had started at offset just now.
+func (s *stopwatch) Reset(offset time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.offset = offset
+	s.started = time.Now()
+	s.pausedAt = time.Time{}
+	s.pausedFor = 0
+}
+
+func (s *stopwatch) Pause() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pausedAt.IsZero() {
+		s.pausedAt = time.Now()
+	}
+}
+
+func (s *stopwatch) Resume() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.pausedAt.IsZero() {
+		s.pausedFor += time.Since(s.pausedAt)
+		s.pausedAt = time.Time{}
+	}
+}
+
+// Elapsed returns the offset the stopwatch was reset to plus the time spent unpaused since then.
+func (s *stopwatch) Elapsed() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.started.IsZero() {
+		return 0
+	}
+
+	end := time.Now()
+	if !s.pausedAt.IsZero() {
+		end = s.pausedAt
+	}
+
+	return s.offset + end.Sub(s.started) - s.pausedFor
+}
@@ -0,0 +1,209 @@
+package wav
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// maxFormatSize is the size of the largest format chunk, that of WAVE_FORMAT_EXTENSIBLE files. Anything past it is
+// skipped, so that a corrupted size can't make the decoder allocate more.
+const maxFormatSize = 40
+
+// unknownSize is used as the data size by writers that can't go back to fill it in, e.g. ffmpeg writing to a pipe.
+const unknownSize = 0xffffffff
+
+// Decoder reads the audio data of a wav stream as raw interleaved samples. Chunks other than the format and data chunks
+// are skipped.
+type Decoder struct {
+	r      io.Reader
+	format Format
+
+	// remaining is the number of data bytes left to read, or -1 if the stream doesn't say.
+	remaining int64
+	// offset is the media time the decoder was opened at, and read is the number of bytes read since.
+	offset time.Duration
+	read   int64
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Open reads the header of the wav stream in r up to the start of its audio data. ErrInvalid is returned if r isn't a
+// wav stream, and ErrUnsupported if its samples aren't integer or floating point PCM.
+func (d *Decoder) Open(r io.Reader) error {
+	d.r = r
+	d.offset = 0
+	d.read = 0
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return ErrInvalid
+	}
+	if !bytes.Equal(header[0:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WAVE")) {
+		return ErrInvalid
+	}
+
+	hasFormat := false
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return ErrInvalid
+		}
+		id, size := string(chunk[0:4]), int64(ByteOrder.Uint32(chunk[4:8]))
+
+		switch {
+		case id == "fmt ":
+			body := make([]byte, min(size, maxFormatSize))
+			if _, err := io.ReadFull(r, body); err != nil {
+				return ErrInvalid
+			}
+			if err := discard(r, size-int64(len(body))+size%2); err != nil {
+				return err
+			}
+
+			format, err := parseFormat(body)
+			if err != nil {
+				return err
+			}
+			d.format = format
+			hasFormat = true
+		case id == "data" && hasFormat:
+			d.remaining = size
+			if size == unknownSize {
+				d.remaining = -1
+			}
+			return nil
+		case id == "data":
+			return ErrInvalid
+		default:
+			// Chunks are padded to an even size.
+			if err := discard(r, size+size%2); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// OpenAt opens r like Open, but skips the audio data up to offset. If r is an io.Seeker, the data is seeked over
+// instead of being read.
+func (d *Decoder) OpenAt(r io.Reader, offset time.Duration) error {
+	if err := d.Open(r); err != nil {
+		return err
+	}
+
+	if offset <= 0 {
+		return nil
+	}
+
+	skip := int64(offset) * int64(d.format.SampleRate) / int64(time.Second) * int64(d.format.BlockAlign())
+	if d.remaining >= 0 {
+		skip = min(skip, d.remaining)
+	}
+
+	if seeker, ok := r.(io.Seeker); ok {
+		if _, err := seeker.Seek(skip, io.SeekCurrent); err != nil {
+			return err
+		}
+	} else if err := discard(r, skip); err != nil && err != ErrInvalid {
+		return err
+	}
+
+	if d.remaining >= 0 {
+		d.remaining -= skip
+	}
+	d.offset = d.duration(skip)
+
+	return nil
+}
+
+// Read reads audio data into p. io.EOF is returned at the end of the data chunk.
+func (d *Decoder) Read(p []byte) (int, error) {
+	if d.r == nil {
+		return 0, ErrInvalid
+	}
+
+	if d.remaining == 0 {
+		return 0, io.EOF
+	}
+	if d.remaining > 0 && int64(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+
+	n, err := d.r.Read(p)
+	d.read += int64(n)
+	if d.remaining > 0 {
+		d.remaining -= int64(n)
+	}
+
+	return n, err
+}
+
+// Format returns the format of the audio data. It is only valid once the decoder has been opened.
+func (d *Decoder) Format() Format {
+	return d.format
+}
+
+// Position returns the media time of the audio data read so far, including the offset the decoder was opened at.
+func (d *Decoder) Position() (time.Duration, bool) {
+	if d.format.ByteRate() == 0 {
+		return 0, false
+	}
+
+	return d.offset + d.duration(d.read), true
+}
+
+func (d *Decoder) Close() error {
+	d.r = nil
+	d.format = Format{}
+	d.remaining = 0
+	d.offset = 0
+	d.read = 0
+	return nil
+}
+
+// duration returns the length of size bytes of audio data.
+func (d *Decoder) duration(size int64) time.Duration {
+	return time.Duration(size / int64(d.format.BlockAlign()) * int64(time.Second) / int64(d.format.SampleRate))
+}
+
+// parseFormat parses the body of a format chunk.
+func parseFormat(b []byte) (Format, error) {
+	if len(b) < 16 {
+		return Format{}, ErrInvalid
+	}
+
+	format := Format{
+		Encoding:      Encoding(ByteOrder.Uint16(b[0:2])),
+		Channels:      int(ByteOrder.Uint16(b[2:4])),
+		SampleRate:    int(ByteOrder.Uint32(b[4:8])),
+		BitsPerSample: int(ByteOrder.Uint16(b[14:16])),
+	}
+
+	// The actual encoding of extensible files is the first two bytes of their sub format GUID.
+	if format.Encoding == extensible {
+		if len(b) < 26 {
+			return Format{}, ErrInvalid
+		}
+		format.Encoding = Encoding(ByteOrder.Uint16(b[24:26]))
+	}
+
+	if !format.valid() {
+		return Format{}, ErrUnsupported
+	}
+
+	return format, nil
+}
+
+// discard reads and drops n bytes from r.
+func discard(r io.Reader, n int64) error {
+	if _, err := io.CopyN(io.Discard, r, n); err != nil {
+		if err == io.EOF {
+			return ErrInvalid
+		}
+		return err
+	}
+
+	return nil
+}
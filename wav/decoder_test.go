@@ -0,0 +1,150 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/olympus-go/apollo/wav"
+)
+
+func TestDecoder_Open(t *testing.T) {
+	type test struct {
+		data   []byte
+		format wav.Format
+		err    error
+	}
+
+	stereo := wav.Format{Encoding: wav.PCM, SampleRate: 48000, Channels: 2, BitsPerSample: 16}
+	mono := wav.Format{Encoding: wav.Float, SampleRate: 44100, Channels: 1, BitsPerSample: 32}
+	audio := bytes.Repeat([]byte{1, 2, 3, 4}, 10)
+	list := chunk("LIST", []byte("odd"))
+
+	tests := map[string]test{
+		"canonical":   {genWav(fmtChunk(1, 2, 48000, 16, nil), audio), stereo, nil},
+		"extensible":  {genWav(fmtChunk(0xfffe, 2, 48000, 16, extension(1)), audio), stereo, nil},
+		"extra_chunk": {genWav(append(list, fmtChunk(1, 2, 48000, 16, nil)...), audio), stereo, nil},
+		"float":       {genWav(fmtChunk(3, 1, 44100, 32, nil), audio), mono, nil},
+		"long_format": {genWav(fmtChunk(1, 2, 48000, 16, make([]byte, 1<<16)), audio), stereo, nil},
+		"huge_format": {append(genWav(nil, nil)[:12], "fmt \xf0\xff\xff\xff"...), wav.Format{}, wav.ErrInvalid},
+		"adpcm":       {genWav(fmtChunk(2, 2, 48000, 4, nil), audio), wav.Format{}, wav.ErrUnsupported},
+		"no_format":   {genWav(nil, audio), wav.Format{}, wav.ErrInvalid},
+		"not_wav":     {[]byte("OggS this is not a wav file at all"), wav.Format{}, wav.ErrInvalid},
+		"truncated":   {genWav(fmtChunk(1, 2, 48000, 16, nil), audio)[:30], wav.Format{}, wav.ErrInvalid},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := wav.NewDecoder()
+			if err := d.Open(bytes.NewReader(tst.data)); !errors.Is(err, tst.err) {
+				t.Fatalf("expected error %v; got %v", tst.err, err)
+			} else if err != nil {
+				return
+			}
+
+			if d.Format() != tst.format {
+				t.Fatalf("expected format %+v; got %+v", tst.format, d.Format())
+			}
+
+			data, err := io.ReadAll(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, audio) {
+				t.Fatalf("expected %d bytes of audio; got %d bytes", len(audio), len(data))
+			}
+		})
+	}
+}
+
+func TestDecoder_OpenAt(t *testing.T) {
+	type test struct {
+		offset   time.Duration
+		seekable bool
+		first    byte
+		position time.Duration
+	}
+
+	// 1 second of 8-bit mono audio at 1kHz, where every sample holds its index divided by 10.
+	audio := make([]byte, 1000)
+	for i := range audio {
+		audio[i] = byte(i / 10)
+	}
+	data := genWav(fmtChunk(1, 1, 1000, 8, nil), audio)
+
+	tests := map[string]test{
+		"start":           {0, false, 0, time.Second},
+		"middle":          {500 * time.Millisecond, false, 50, time.Second},
+		"seekable_middle": {500 * time.Millisecond, true, 50, time.Second},
+		"past_end":        {2 * time.Second, true, 0, time.Second},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			var r io.Reader = bytes.NewReader(data)
+			if !tst.seekable {
+				r = struct{ io.Reader }{r}
+			}
+
+			d := wav.NewDecoder()
+			if err := d.OpenAt(r, tst.offset); err != nil {
+				t.Fatal(err)
+			}
+
+			read, err := io.ReadAll(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(read) > 0 && read[0] != tst.first {
+				t.Fatalf("expected sample %d first; got %d", tst.first, read[0])
+			}
+			if position, ok := d.Position(); !ok || position != tst.position {
+				t.Fatalf("expected position %s; got %s", tst.position, position)
+			}
+		})
+	}
+}
+
+func genWav(chunks []byte, audio []byte) []byte {
+	body := append([]byte("WAVE"), chunks...)
+	body = append(body, chunk("data", audio)...)
+
+	return chunk("RIFF", body)
+}
+
+func chunk(id string, body []byte) []byte {
+	b := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	b = append(b, body...)
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+func fmtChunk(encoding uint16, channels uint16, sampleRate uint32, bits uint16, ext []byte) []byte {
+	blockAlign := channels * bits / 8
+
+	b := binary.LittleEndian.AppendUint16(nil, encoding)
+	b = binary.LittleEndian.AppendUint16(b, channels)
+	b = binary.LittleEndian.AppendUint32(b, sampleRate)
+	b = binary.LittleEndian.AppendUint32(b, sampleRate*uint32(blockAlign))
+	b = binary.LittleEndian.AppendUint16(b, blockAlign)
+	b = binary.LittleEndian.AppendUint16(b, bits)
+	b = append(b, ext...)
+
+	return chunk("fmt ", b)
+}
+
+// extension returns the extensible part of a format chunk with the given sub format.
+func extension(encoding uint16) []byte {
+	b := binary.LittleEndian.AppendUint16(nil, 22)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = binary.LittleEndian.AppendUint32(b, 3)
+	b = binary.LittleEndian.AppendUint16(b, encoding)
+
+	return append(b, "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"...)
+}
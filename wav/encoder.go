@@ -0,0 +1,118 @@
+package wav
+
+import (
+	"io"
+)
+
+// Encoder writes raw interleaved samples as a wav stream. The header is written along with the first data. Its sizes
+// are filled in by Close if the underlying writer is an io.WriteSeeker, and are otherwise left unknown, which most
+// readers handle by reading until the end of the stream.
+type Encoder struct {
+	w      io.Writer
+	format Format
+
+	written int64
+	started bool
+	closed  bool
+}
+
+// NewEncoder creates an Encoder that writes audio data of the given format to w.
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{
+		w:      w,
+		format: format,
+	}
+}
+
+// Write writes p as audio data, writing the header first if it hasn't been yet. ErrUnsupported is returned if the
+// Encoder's format can't be written.
+func (e *Encoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	n, err := e.w.Write(p)
+	e.written += int64(n)
+
+	return n, err
+}
+
+// Close pads the audio data to an even size and fills in the sizes in the header if possible. A header is still written
+// if no data was. Close doesn't close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	if e.written%2 == 1 {
+		if _, err := e.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+
+	ws, ok := e.w.(io.WriteSeeker)
+	if !ok || e.written+headerSize-8 > unknownSize-1 {
+		return nil
+	}
+
+	if _, err := ws.Seek(-(headerSize + e.written + e.written%2), io.SeekCurrent); err != nil {
+		return err
+	}
+	if _, err := ws.Write(header(e.format, e.written)); err != nil {
+		return err
+	}
+	_, err := ws.Seek(e.written+e.written%2, io.SeekCurrent)
+
+	return err
+}
+
+func (e *Encoder) writeHeader() error {
+	if e.started {
+		return nil
+	}
+
+	if !e.format.valid() {
+		return ErrUnsupported
+	}
+
+	e.started = true
+	_, err := e.w.Write(header(e.format, unknownSize))
+
+	return err
+}
+
+// header returns a canonical 44 byte wav header for dataSize bytes of audio data of format.
+func header(format Format, dataSize int64) []byte {
+	riffSize := uint32(unknownSize)
+	if dataSize != unknownSize {
+		riffSize = uint32(headerSize - 8 + dataSize + dataSize%2)
+	}
+
+	b := make([]byte, 0, headerSize)
+	b = append(b, "RIFF"...)
+	b = ByteOrder.AppendUint32(b, riffSize)
+	b = append(b, "WAVE"...)
+
+	b = append(b, "fmt "...)
+	b = ByteOrder.AppendUint32(b, 16)
+	b = ByteOrder.AppendUint16(b, uint16(format.Encoding))
+	b = ByteOrder.AppendUint16(b, uint16(format.Channels))
+	b = ByteOrder.AppendUint32(b, uint32(format.SampleRate))
+	b = ByteOrder.AppendUint32(b, uint32(format.ByteRate()))
+	b = ByteOrder.AppendUint16(b, uint16(format.BlockAlign()))
+	b = ByteOrder.AppendUint16(b, uint16(format.BitsPerSample))
+
+	b = append(b, "data"...)
+	b = ByteOrder.AppendUint32(b, uint32(dataSize))
+
+	return b
+}
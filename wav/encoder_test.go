@@ -0,0 +1,100 @@
+package wav_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/olympus-go/apollo/wav"
+)
+
+func TestEncoder_Write(t *testing.T) {
+	type test struct {
+		format   wav.Format
+		data     []byte
+		seekable bool
+	}
+
+	stereo := wav.Format{Encoding: wav.PCM, SampleRate: 48000, Channels: 2, BitsPerSample: 16}
+	float := wav.Format{Encoding: wav.Float, SampleRate: 44100, Channels: 1, BitsPerSample: 32}
+	mono8 := wav.Format{Encoding: wav.PCM, SampleRate: 8000, Channels: 1, BitsPerSample: 8}
+
+	tests := map[string]test{
+		"streamed":       {stereo, bytes.Repeat([]byte{1, 2, 3, 4}, 1000), false},
+		"seekable":       {stereo, bytes.Repeat([]byte{1, 2, 3, 4}, 1000), true},
+		"float":          {float, bytes.Repeat([]byte{0, 0, 0x80, 0x3f}, 100), true},
+		"odd_size":       {mono8, []byte{1, 2, 3}, true},
+		"empty":          {stereo, nil, true},
+		"empty_streamed": {stereo, nil, false},
+	}
+
+	for name, tst := range tests {
+		t.Run(name, func(t *testing.T) {
+			var w io.Writer
+			var f *os.File
+			var buf bytes.Buffer
+			if tst.seekable {
+				var err error
+				if f, err = os.Create(filepath.Join(t.TempDir(), "test.wav")); err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				w = f
+			} else {
+				w = &buf
+			}
+
+			e := wav.NewEncoder(w, tst.format)
+			// Write in two parts, to check that the header is only written once.
+			half := len(tst.data) / 2
+			if _, err := e.Write(tst.data[:half]); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := e.Write(tst.data[half:]); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var r io.Reader = &buf
+			if tst.seekable {
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					t.Fatal(err)
+				}
+				r = f
+			}
+
+			d := wav.NewDecoder()
+			if err := d.Open(r); err != nil {
+				t.Fatal(err)
+			}
+			if d.Format() != tst.format {
+				t.Fatalf("expected format %+v; got %+v", tst.format, d.Format())
+			}
+
+			data, err := io.ReadAll(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Streamed files have no size, so the padding byte is read as data.
+			if !tst.seekable && len(tst.data)%2 == 1 {
+				data = data[:len(data)-1]
+			}
+			if !bytes.Equal(data, tst.data) {
+				t.Fatalf("expected %d bytes of data; got %d bytes", len(tst.data), len(data))
+			}
+		})
+	}
+}
+
+func TestEncoder_Unsupported(t *testing.T) {
+	e := wav.NewEncoder(io.Discard, wav.Format{Encoding: wav.Float, SampleRate: 48000, Channels: 2, BitsPerSample: 16})
+
+	if _, err := e.Write([]byte{0, 0}); !errors.Is(err, wav.ErrUnsupported) {
+		t.Fatalf("expected %q error; got %v", wav.ErrUnsupported, err)
+	}
+}
@@ -0,0 +1,8 @@
+package wav
+
+import (
+	"errors"
+)
+
+var ErrInvalid = errors.New("invalid wav stream")
+var ErrUnsupported = errors.New("unsupported wav encoding")
@@ -0,0 +1,88 @@
+package wav
+
+import (
+	"encoding/binary"
+
+	"github.com/olympus-go/apollo"
+)
+
+// Implementation spec: http://soundfile.sapp.org/doc/WaveFormat/
+
+// ByteOrder is the byte order used by wav files.
+var ByteOrder = binary.LittleEndian
+
+// Encoding is the format tag of a wav file, which tells how its samples are encoded.
+type Encoding uint16
+
+const (
+	// PCM is integer samples. 8-bit samples are unsigned, larger ones are signed.
+	PCM Encoding = 0x0001
+	// Float is IEEE floating point samples.
+	Float Encoding = 0x0003
+	// extensible is used by files whose actual encoding is in a sub format GUID.
+	extensible Encoding = 0xfffe
+)
+
+// headerSize is the size of the header written by Encoder, up to the start of the audio data.
+const headerSize = 44
+
+// Format describes the audio data of a wav file.
+type Format struct {
+	Encoding      Encoding
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// FromPCMFormat returns the Format of wav files holding PCM in f.
+func FromPCMFormat(f apollo.PCMFormat) Format {
+	format := Format{Encoding: PCM, SampleRate: f.SampleRate, Channels: f.Channels, BitsPerSample: 16}
+	if f.SampleFormat == apollo.F32LE {
+		format.Encoding = Float
+		format.BitsPerSample = 32
+	}
+
+	return format
+}
+
+// PCMFormat returns the apollo.PCMFormat matching f. ok is false if f's samples can't be represented by one, which is
+// the case for anything but 16-bit integer and 32-bit floating point samples.
+func (f Format) PCMFormat() (format apollo.PCMFormat, ok bool) {
+	format = apollo.PCMFormat{SampleRate: f.SampleRate, Channels: f.Channels}
+
+	switch {
+	case f.Encoding == PCM && f.BitsPerSample == 16:
+		format.SampleFormat = apollo.S16LE
+	case f.Encoding == Float && f.BitsPerSample == 32:
+		format.SampleFormat = apollo.F32LE
+	default:
+		return apollo.PCMFormat{}, false
+	}
+
+	return format, true
+}
+
+// BlockAlign returns the size in bytes of one sample across all channels.
+func (f Format) BlockAlign() int {
+	return f.Channels * (f.BitsPerSample / 8)
+}
+
+// ByteRate returns the number of bytes per second of audio.
+func (f Format) ByteRate() int {
+	return f.SampleRate * f.BlockAlign()
+}
+
+func (f Format) valid() bool {
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return false
+	}
+
+	switch f.Encoding {
+	case PCM:
+		return f.BitsPerSample == 8 || f.BitsPerSample == 16 || f.BitsPerSample == 24 || f.BitsPerSample == 32
+	case Float:
+		return f.BitsPerSample == 32 || f.BitsPerSample == 64
+	default:
+		return false
+	}
+}